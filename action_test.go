@@ -0,0 +1,245 @@
+package parser_test
+
+import (
+	"errors"
+	"testing"
+
+	llp "github.com/romshark/llparser"
+	"github.com/stretchr/testify/require"
+)
+
+// ActionLog records executed and undone actions
+type ActionLog struct {
+	Executed []string
+	Undone   []string
+}
+
+func newLoggedRule(designation string, pattern llp.Pattern) *llp.Rule {
+	return &llp.Rule{
+		Designation: designation,
+		Pattern:     pattern,
+		Action: func(ctx *llp.Context, f llp.Fragment) error {
+			log := ctx.Value().(*ActionLog)
+			log.Executed = append(log.Executed, f.Begin().String())
+			return nil
+		},
+		Undo: func(ctx *llp.Context, f llp.Fragment) {
+			log := ctx.Value().(*ActionLog)
+			log.Undone = append(log.Undone, f.Begin().String())
+		},
+	}
+}
+
+func parseLogged(
+	t *testing.T,
+	grammar *llp.Rule,
+	deferActions bool,
+	src string,
+) (*ActionLog, error) {
+	pr := newParser(t, grammar, nil)
+	pr.DeferActions = deferActions
+	log := &ActionLog{}
+	_, err := pr.ParseWith(newSource(src), llp.ParseOptions{Value: log})
+	return log, err
+}
+
+func TestActionBacktrackingEither(t *testing.T) {
+	ruleA := newLoggedRule("a", &llp.Exact{Expectation: []rune("a")})
+	grammar := &llp.Rule{
+		Designation: "main",
+		Pattern: llp.Either{
+			llp.Sequence{ruleA, &llp.Exact{Expectation: []rune("!")}},
+			llp.Sequence{ruleA, &llp.Exact{Expectation: []rune("?")}},
+		},
+	}
+
+	t.Run("Immediate", func(t *testing.T) {
+		log, err := parseLogged(t, grammar, false, "a?")
+		require.NoError(t, err)
+		require.Equal(t, []string{"test.txt:1:1", "test.txt:1:1"}, log.Executed)
+		require.Equal(t, []string{"test.txt:1:1"}, log.Undone)
+	})
+
+	t.Run("Deferred", func(t *testing.T) {
+		log, err := parseLogged(t, grammar, true, "a?")
+		require.NoError(t, err)
+		require.Equal(t, []string{"test.txt:1:1"}, log.Executed)
+		require.Len(t, log.Undone, 0)
+	})
+}
+
+func TestActionBacktrackingRepeated(t *testing.T) {
+	ruleA := newLoggedRule("a", &llp.Exact{Expectation: []rune("a")})
+	grammar := &llp.Rule{
+		Designation: "main",
+		Pattern: llp.Sequence{
+			&llp.Repeated{
+				Pattern: llp.Sequence{ruleA, termSeparator},
+			},
+			ruleA,
+		},
+	}
+
+	t.Run("Immediate", func(t *testing.T) {
+		log, err := parseLogged(t, grammar, false, "a,a,a")
+		require.NoError(t, err)
+		require.Equal(t, []string{
+			"test.txt:1:1",
+			"test.txt:1:3",
+			"test.txt:1:5",
+			"test.txt:1:5",
+		}, log.Executed)
+		require.Equal(t, []string{"test.txt:1:5"}, log.Undone)
+	})
+
+	t.Run("Deferred", func(t *testing.T) {
+		log, err := parseLogged(t, grammar, true, "a,a,a")
+		require.NoError(t, err)
+		require.Equal(t, []string{
+			"test.txt:1:1",
+			"test.txt:1:3",
+			"test.txt:1:5",
+		}, log.Executed)
+		require.Len(t, log.Undone, 0)
+	})
+}
+
+func TestActionBacktrackingNested(t *testing.T) {
+	ruleA := newLoggedRule("a", &llp.Exact{Expectation: []rune("a")})
+	ruleB := newLoggedRule("b", &llp.Exact{Expectation: []rune("b")})
+	ruleList := newLoggedRule("list", &llp.Repeated{
+		Min: 1,
+		Pattern: llp.Either{
+			llp.Sequence{ruleA, ruleB},
+			ruleA,
+		},
+	})
+	grammar := &llp.Rule{
+		Designation: "main",
+		Pattern: llp.Either{
+			// The first option fails after matching the entire list
+			llp.Sequence{ruleList, &llp.Exact{Expectation: []rune("!")}},
+			llp.Sequence{ruleList, &llp.Exact{Expectation: []rune("?")}},
+		},
+	}
+
+	t.Run("Immediate", func(t *testing.T) {
+		log, err := parseLogged(t, grammar, false, "aba?")
+		require.NoError(t, err)
+		require.Equal(t, []string{
+			// First option
+			"test.txt:1:1", // a
+			"test.txt:1:2", // b
+			"test.txt:1:3", // a (followed by b)
+			"test.txt:1:3", // a
+			"test.txt:1:1", // list
+			// Second option
+			"test.txt:1:1", // a
+			"test.txt:1:2", // b
+			"test.txt:1:3", // a (followed by b)
+			"test.txt:1:3", // a
+			"test.txt:1:1", // list
+		}, log.Executed)
+		require.Equal(t, []string{
+			// Second option of the list item
+			"test.txt:1:3", // a (followed by b)
+			// First option
+			"test.txt:1:1", // list
+			"test.txt:1:3", // a
+			"test.txt:1:2", // b
+			"test.txt:1:1", // a
+			// Second option of the list item
+			"test.txt:1:3", // a (followed by b)
+		}, log.Undone)
+	})
+
+	t.Run("Deferred", func(t *testing.T) {
+		log, err := parseLogged(t, grammar, true, "aba?")
+		require.NoError(t, err)
+		require.Equal(t, []string{
+			"test.txt:1:1", // a
+			"test.txt:1:2", // b
+			"test.txt:1:3", // a
+			"test.txt:1:1", // list
+		}, log.Executed)
+		require.Len(t, log.Undone, 0)
+	})
+}
+
+func TestActionFailedParse(t *testing.T) {
+	ruleA := newLoggedRule("a", &llp.Exact{Expectation: []rune("a")})
+	grammar := &llp.Rule{
+		Designation: "main",
+		Pattern:     &llp.Repeated{Min: 1, Pattern: ruleA},
+	}
+
+	t.Run("Immediate", func(t *testing.T) {
+		log, err := parseLogged(t, grammar, false, "aab")
+		require.Error(t, err)
+		require.Equal(t, []string{"test.txt:1:1", "test.txt:1:2"}, log.Executed)
+		require.Equal(t, []string{"test.txt:1:2", "test.txt:1:1"}, log.Undone)
+	})
+
+	t.Run("Deferred", func(t *testing.T) {
+		log, err := parseLogged(t, grammar, true, "aab")
+		require.Error(t, err)
+		require.Len(t, log.Executed, 0)
+		require.Len(t, log.Undone, 0)
+	})
+}
+
+func TestActionDeferredErr(t *testing.T) {
+	expectedErr := errors.New("custom error")
+	pr := newParser(t, &llp.Rule{
+		Designation: "main",
+		Pattern: llp.Sequence{
+			testR_foo,
+			&llp.Rule{
+				Designation: "a",
+				Pattern:     &llp.Exact{Expectation: []rune("a")},
+				Action: func(*llp.Context, llp.Fragment) error {
+					return expectedErr
+				},
+			},
+		},
+	}, nil)
+	pr.DeferActions = true
+
+	mainFrag, err := pr.Parse(newSource("fooa"))
+	require.Error(t, err)
+	require.IsType(t, &llp.Err{}, err)
+	require.Equal(t, expectedErr, err.(*llp.Err).Err)
+	require.Equal(t, "custom error at test.txt:1:4", err.Error())
+	require.Nil(t, mainFrag)
+}
+
+func TestActionDeferredErrRule(t *testing.T) {
+	pr := newParser(t, &llp.Rule{
+		Designation: "main",
+		Pattern:     testR_foo,
+	}, &llp.Rule{
+		Designation: "error",
+		Pattern:     testR_bar,
+		Action: func(*llp.Context, llp.Fragment) error {
+			return errors.New("expected foo, got bar")
+		},
+	})
+	pr.DeferActions = true
+
+	mainFrag, err := pr.Parse(newSource("bar"))
+	require.Error(t, err)
+	require.Equal(t, "expected foo, got bar at test.txt:1:1", err.Error())
+	require.Nil(t, mainFrag)
+}
+
+func TestActionDeferredScopes(t *testing.T) {
+	pr := newParser(t, newSymbolGrammar(), nil)
+	pr.DeferActions = true
+
+	_, err := pr.Parse(newSource("$a;{$a;}$b!"))
+	require.NoError(t, err)
+
+	_, err = pr.Parse(newSource("$a;{$b;}$a;"))
+	require.Error(t, err)
+	require.Equal(t, `"a" redeclared at test.txt:1:9`, err.Error())
+}
@@ -0,0 +1,279 @@
+// llpkinds generates a llparser kind registry from the constants
+// of a fragment kind type.
+//
+// Given the package
+//
+//	package lang
+//
+//	type FragKind = llp.FragmentKind
+//
+//	const (
+//		_ FragKind = iota
+//		FrSpace
+//
+//		//llp:category operator
+//		FrOprAnd
+//	)
+//
+// running
+//
+//	llpkinds -type FragKind -trimprefix Fr
+//
+// in its directory generates the file fragkind_kinds.go declaring
+//
+//	var Kinds = func() *llp.KindRegistry { ... }()
+//
+// which registers FrSpace as "Space" and FrOprAnd as "OprAnd" of the
+// category "operator". Blank constants are ignored. Constants of
+// qualified types like llp.FragmentKind are found by either the qualified
+// or the plain type name. Files excluded by build constraints are ignored.
+// It's typically invoked by a go:generate directive next to the const block:
+//
+//	//go:generate go run github.com/romshark/llparser/cmd/llpkinds -type FragKind
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"go/ast"
+	"go/build"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// categoryDirective prefixes the category comment of a constant
+const categoryDirective = "//llp:category "
+
+var (
+	flagType       = flag.String("type", "", "name of the fragment kind type")
+	flagTrimPrefix = flag.String("trimprefix", "", "prefix to trim from names")
+	flagVar        = flag.String("var", "Kinds", "name of the registry var")
+	flagOutput     = flag.String("output", "", "output file name")
+)
+
+// kindConst represents a constant of the fragment kind type
+type kindConst struct {
+	Ident    string
+	Name     string
+	Category string
+}
+
+// config defines the generated registry
+type config struct {
+	Type       string
+	TrimPrefix string
+	Var        string
+	Command    string
+}
+
+func main() {
+	log.SetFlags(0)
+	log.SetPrefix("llpkinds: ")
+	flag.Parse()
+	if *flagType == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	dir := "."
+	if flag.NArg() > 0 {
+		dir = flag.Arg(0)
+	}
+	pkgName, files, err := parseDir(dir)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	src, err := generate(pkgName, files, config{
+		Type:       *flagType,
+		TrimPrefix: *flagTrimPrefix,
+		Var:        *flagVar,
+		Command:    "llpkinds " + strings.Join(os.Args[1:], " "),
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	output := *flagOutput
+	if output == "" {
+		// Name the file after the type name of qualified types
+		name := (*flagType)[strings.LastIndex(*flagType, ".")+1:]
+		output = filepath.Join(dir, strings.ToLower(name)+"_kinds.go")
+	}
+	if err := ioutil.WriteFile(output, src, 0644); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// parseDir parses the non-test Go files of the package in the given
+// directory matching the build constraints of the current platform.
+// Returns the name of the package and its files in alphabetical order
+func parseDir(dir string) (string, []*ast.File, error) {
+	pkg, err := build.ImportDir(dir, 0)
+	if err != nil {
+		return "", nil, err
+	}
+	fset := token.NewFileSet()
+	files := make([]*ast.File, len(pkg.GoFiles))
+	for ix, name := range pkg.GoFiles {
+		files[ix], err = parser.ParseFile(
+			fset, filepath.Join(dir, name), nil, parser.ParseComments,
+		)
+		if err != nil {
+			return "", nil, err
+		}
+	}
+	return pkg.Name, files, nil
+}
+
+// typeName returns the name of the type expression which is either
+// an identifier or a selector qualified by the package name.
+// Returns an empty string for any other type expression
+func typeName(expr ast.Expr) string {
+	switch expr := expr.(type) {
+	case *ast.Ident:
+		return expr.Name
+	case *ast.SelectorExpr:
+		if pkg, ok := expr.X.(*ast.Ident); ok {
+			return pkg.Name + "." + expr.Sel.Name
+		}
+	}
+	return ""
+}
+
+// matchesType returns true if the type name denotes the given type.
+// An unqualified type also matches types of any package of the same name
+func matchesType(name, typ string) bool {
+	if name == "" {
+		return false
+	}
+	if name == typ {
+		return true
+	}
+	return !strings.Contains(typ, ".") &&
+		strings.HasSuffix(name, "."+typ)
+}
+
+// findConsts returns the constants of the given type in declaration order
+func findConsts(files []*ast.File, conf config) []kindConst {
+	var consts []kindConst
+	for _, file := range files {
+		for _, decl := range file.Decls {
+			gen, ok := decl.(*ast.GenDecl)
+			if !ok || gen.Tok != token.CONST {
+				continue
+			}
+			// Specs without type and values inherit the type
+			// of the previous spec
+			typ := ""
+			for _, spec := range gen.Specs {
+				vspec := spec.(*ast.ValueSpec)
+				if vspec.Type != nil {
+					typ = typeName(vspec.Type)
+				} else if len(vspec.Values) > 0 {
+					typ = ""
+				}
+				if !matchesType(typ, conf.Type) {
+					continue
+				}
+				category := findCategory(vspec.Doc, vspec.Comment)
+				for _, name := range vspec.Names {
+					if name.Name == "_" {
+						continue
+					}
+					consts = append(consts, kindConst{
+						Ident:    name.Name,
+						Name:     strings.TrimPrefix(name.Name, conf.TrimPrefix),
+						Category: category,
+					})
+				}
+			}
+		}
+	}
+	return consts
+}
+
+// findCategory returns the category of the first category directive
+func findCategory(groups ...*ast.CommentGroup) string {
+	for _, group := range groups {
+		if group == nil {
+			continue
+		}
+		for _, comment := range group.List {
+			if strings.HasPrefix(comment.Text, categoryDirective) {
+				return strings.TrimSpace(
+					strings.TrimPrefix(comment.Text, categoryDirective),
+				)
+			}
+		}
+	}
+	return ""
+}
+
+// generate generates the source file of the kind registry
+func generate(
+	pkgName string,
+	files []*ast.File,
+	conf config,
+) ([]byte, error) {
+	consts := findConsts(files, conf)
+	if len(consts) < 1 {
+		return nil, fmt.Errorf("no constants of type %s found", conf.Type)
+	}
+	names := map[string]string{}
+	for _, cn := range consts {
+		if cn.Name == "" {
+			return nil, fmt.Errorf(
+				"constant %s has no name after trimming prefix %q",
+				cn.Ident,
+				conf.TrimPrefix,
+			)
+		}
+		if ident, ok := names[cn.Name]; ok {
+			return nil, fmt.Errorf(
+				"constants %s and %s share the name %q",
+				ident,
+				cn.Ident,
+				cn.Name,
+			)
+		}
+		names[cn.Name] = cn.Ident
+	}
+	if conf.Var == "" {
+		return nil, errors.New("missing registry variable name")
+	}
+
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "// Code generated by %q; DO NOT EDIT.\n\n", conf.Command)
+	fmt.Fprintf(&b, "package %s\n\n", pkgName)
+	b.WriteString("import llp \"github.com/romshark/llparser\"\n\n")
+	fmt.Fprintf(
+		&b,
+		"// %s holds the names of the %s constants\n",
+		conf.Var,
+		conf.Type,
+	)
+	fmt.Fprintf(&b, "var %s = func() *llp.KindRegistry {\n", conf.Var)
+	b.WriteString("kinds := &llp.KindRegistry{}\n")
+	for _, cn := range consts {
+		fmt.Fprintf(&b, "kinds.MustRegister(%s, %q)\n", cn.Ident, cn.Name)
+		if cn.Category != "" {
+			fmt.Fprintf(
+				&b,
+				"kinds.Categorize(%s, %q)\n",
+				cn.Ident,
+				cn.Category,
+			)
+		}
+	}
+	b.WriteString("return kinds\n}()\n")
+	return format.Source(b.Bytes())
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func parseFile(t *testing.T, src string) []*ast.File {
+	file, err := parser.ParseFile(
+		token.NewFileSet(), "kinds.go", src, parser.ParseComments,
+	)
+	require.NoError(t, err)
+	return []*ast.File{file}
+}
+
+func TestGenerate(t *testing.T) {
+	files := parseFile(t, `package lang
+
+type FragKind = int
+
+const (
+	_ FragKind = iota
+
+	// FrSpace represents spaces
+	FrSpace
+
+	//llp:category operator
+	FrOprAnd
+	FrOprOr //llp:category operator
+)
+
+const (
+	Other = 1
+	FrUnrelated
+)
+`)
+	src, err := generate("lang", files, config{
+		Type:       "FragKind",
+		TrimPrefix: "Fr",
+		Var:        "Kinds",
+		Command:    "llpkinds -type FragKind -trimprefix Fr",
+	})
+	require.NoError(t, err)
+	require.Equal(t, `// Code generated by "llpkinds -type FragKind -trimprefix Fr"; DO NOT EDIT.
+
+package lang
+
+import llp "github.com/romshark/llparser"
+
+// Kinds holds the names of the FragKind constants
+var Kinds = func() *llp.KindRegistry {
+	kinds := &llp.KindRegistry{}
+	kinds.MustRegister(FrSpace, "Space")
+	kinds.MustRegister(FrOprAnd, "OprAnd")
+	kinds.Categorize(FrOprAnd, "operator")
+	kinds.MustRegister(FrOprOr, "OprOr")
+	kinds.Categorize(FrOprOr, "operator")
+	return kinds
+}()
+`, string(src))
+}
+
+func TestFindConstsQualified(t *testing.T) {
+	files := parseFile(t, `package lang
+
+import llp "github.com/romshark/llparser"
+
+const (
+	FrSpace llp.FragmentKind = iota
+	FrWord
+)
+
+const FrOther other.FragmentKind = 1
+`)
+	for _, typ := range []string{"llp.FragmentKind", "FragmentKind"} {
+		consts := findConsts(files, config{Type: typ, TrimPrefix: "Fr"})
+		if typ == "FragmentKind" {
+			// The plain name matches the types of all packages
+			require.Len(t, consts, 3)
+			consts = consts[:2]
+		}
+		require.Equal(t, []kindConst{
+			{Ident: "FrSpace", Name: "Space"},
+			{Ident: "FrWord", Name: "Word"},
+		}, consts)
+	}
+}
+
+func TestParseDir(t *testing.T) {
+	dir, err := ioutil.TempDir("", "llpkinds")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	for name, src := range map[string]string{
+		"kinds.go": "package lang\n\nconst FrA FragKind = 1\n",
+		// Files excluded by build constraints belong to other packages
+		"gen.go":         "// +build ignore\n\npackage main\n",
+		"kinds_test.go":  "package lang_test\n",
+		"other_plan9.go": "package other\n",
+	} {
+		require.NoError(t, ioutil.WriteFile(
+			filepath.Join(dir, name), []byte(src), 0644,
+		))
+	}
+
+	pkgName, files, err := parseDir(dir)
+	require.NoError(t, err)
+	require.Equal(t, "lang", pkgName)
+	require.Len(t, files, 1)
+	require.Len(t, findConsts(files, config{Type: "FragKind"}), 1)
+}
+
+func TestGenerateErr(t *testing.T) {
+	for name, test := range map[string]struct {
+		Src      string
+		Expected string
+	}{
+		"No constants": {
+			"package lang\n\nconst A = 1\n",
+			"no constants of type FragKind found",
+		},
+		"Empty name": {
+			"package lang\n\nconst Fr FragKind = 1\n",
+			`constant Fr has no name after trimming prefix "Fr"`,
+		},
+		"Duplicate name": {
+			"package lang\n\nconst (\n\tFrA FragKind = iota\n\tA\n)\n",
+			`constants FrA and A share the name "A"`,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := generate("lang", parseFile(t, test.Src), config{
+				Type:       "FragKind",
+				TrimPrefix: "Fr",
+				Var:        "Kinds",
+			})
+			require.Error(t, err)
+			require.Equal(t, test.Expected, err.Error())
+		})
+	}
+}
@@ -4,7 +4,42 @@ package parser
 type Construct struct {
 	*Token
 	VElements []Fragment
+
+	// VLabels holds the labels of the element fragments
+	// and is nil if none of the elements are labeled
+	VLabels []string
 }
 
 // Elements returns the element fragments of the construct fragment
 func (ct *Construct) Elements() []Fragment { return ct.VElements }
+
+// Label returns the label of the element at the given index
+// or an empty string if the element isn't labeled
+func (ct *Construct) Label(index int) string {
+	if index < 0 || index >= len(ct.VLabels) {
+		return ""
+	}
+	return ct.VLabels[index]
+}
+
+// Child returns the first element labeled with the given name
+// or nil if there's no such element
+func (ct *Construct) Child(name string) Fragment {
+	for ix, label := range ct.VLabels {
+		if label == name {
+			return ct.VElements[ix]
+		}
+	}
+	return nil
+}
+
+// Children returns all elements labeled with the given name
+func (ct *Construct) Children(name string) []Fragment {
+	var children []Fragment
+	for ix, label := range ct.VLabels {
+		if label == name {
+			children = append(children, ct.VElements[ix])
+		}
+	}
+	return children
+}
@@ -0,0 +1,359 @@
+package parser
+
+import "errors"
+
+// StackFrame represents an active rule on the rule stack
+type StackFrame struct {
+	Rule  *Rule
+	Begin Cursor
+}
+
+// symbol represents a declared symbol table entry
+type symbol struct {
+	name  string
+	value interface{}
+}
+
+// journalOp represents the type of a journal entry
+type journalOp int
+
+const (
+	_ journalOp = iota
+	opAction
+	opOpenScope
+	opCloseScope
+)
+
+// journalEntry represents an entry of the action journal
+type journalEntry struct {
+	op    journalOp
+	rule  *Rule
+	frag  Fragment
+	stack []StackFrame
+}
+
+// mark represents a backtracking position in the context's history
+type mark struct {
+	symbols     int
+	journal     int
+	diagnostics int
+}
+
+// Context represents the context of a single parse.
+// It's passed to actions and predicates and carries
+// the user value, the rule stack and the scoped symbol table
+type Context struct {
+	value        interface{}
+	stack        []StackFrame
+	symbols      []symbol
+	scopes       []int
+	deferActions bool
+	journal      []journalEntry
+	diagnostics  []Diagnostic
+
+	// choices holds the number of cuts passed in each choice point
+	choices []uint
+
+	// ruleErrs holds the errors of the matched error-rules
+	// of failed rules by the index of the failed rules
+	ruleErrs map[uint]error
+
+	// recovery holds the positions syntax errors are recovered from
+	// and is nil unless the parse is tolerant
+	recovery map[uint]bool
+
+	// speculating is greater than 0 while matching patterns
+	// speculatively during recovery
+	speculating int
+
+	// follow holds the patterns following the current ones
+	// in the enclosing sequences and repetitions
+	follow []following
+
+	// repair is the number of tokens the parse must advance by
+	// after a single-token repair and is 0 if repairs are disabled
+	repair uint
+
+	// tokens counts the matched terminals
+	tokens uint
+
+	// farthest is the index of the farthest mismatching terminal
+	farthest uint
+
+	// errStack holds the rule stack of errStackOf, the latest syntax error
+	// that left a rule, and is reused to avoid allocating a stack for
+	// every syntax error that is backtracked from
+	errStack   []StackFrame
+	errStackOf *ErrUnexpectedToken
+}
+
+func newContext(value interface{}, deferActions bool) *Context {
+	return &Context{value: value, deferActions: deferActions}
+}
+
+// Value returns the user value passed to Parser.ParseWith
+func (ctx *Context) Value() interface{} { return ctx.value }
+
+// Stack returns the stack of currently active rules
+// where the last frame is the innermost rule.
+// The returned slice must not be modified
+func (ctx *Context) Stack() []StackFrame { return ctx.stack }
+
+// Declare declares a symbol in the current scope.
+// Declarations are unwound when the parser backtracks
+// or when the scope they were declared in is closed
+func (ctx *Context) Declare(name string, value interface{}) {
+	ctx.symbols = append(ctx.symbols, symbol{name: name, value: value})
+}
+
+// Lookup looks up a symbol in the current and all enclosing scopes
+// returning the innermost declaration
+func (ctx *Context) Lookup(name string) (interface{}, bool) {
+	for ix := len(ctx.symbols) - 1; ix >= 0; ix-- {
+		if ctx.symbols[ix].name == name {
+			return ctx.symbols[ix].value, true
+		}
+	}
+	return nil, false
+}
+
+// LookupLocal looks up a symbol in the current scope only
+func (ctx *Context) LookupLocal(name string) (interface{}, bool) {
+	begin := 0
+	if len(ctx.scopes) > 0 {
+		begin = ctx.scopes[len(ctx.scopes)-1]
+	}
+	for ix := len(ctx.symbols) - 1; ix >= begin; ix-- {
+		if ctx.symbols[ix].name == name {
+			return ctx.symbols[ix].value, true
+		}
+	}
+	return nil, false
+}
+
+// Report reports a diagnostic that doesn't abort the parse.
+// Diagnostics are discarded when the parser backtracks
+// and are collected in Result.Diagnostics otherwise
+func (ctx *Context) Report(diag Diagnostic) {
+	ctx.diagnostics = append(ctx.diagnostics, diag)
+}
+
+// actionErr handles an error returned by the action of the fragment.
+// Diagnostics are located at the fragment if they have no location.
+// Diagnostics of a severity other than SeverityError are reported instead
+func (ctx *Context) actionErr(err error, frag Fragment) error {
+	var diag *Diagnostic
+	if !errors.As(err, &diag) {
+		return &Err{
+			Err:   err,
+			At:    frag.Begin(),
+			Code:  CodeAction,
+			Stack: append([]StackFrame(nil), ctx.stack...),
+		}
+	}
+	located := *diag
+	if located.Begin.Line == 0 {
+		located.Begin, located.End = frag.Begin(), frag.End()
+	}
+	if located.Severity != SeverityError {
+		ctx.Report(located)
+		return nil
+	}
+	return &located
+}
+
+// ruleErr returns the error of the error-rule of a failed rule
+// causing the given error or located at its position if any
+func (ctx *Context) ruleErr(err *ErrUnexpectedToken) error {
+	if err.ruleErr != nil {
+		return err.ruleErr
+	}
+	return ctx.ruleErrs[err.At.Index]
+}
+
+// ScopeDepth returns the number of currently open scopes
+func (ctx *Context) ScopeDepth() int { return len(ctx.scopes) }
+
+func (ctx *Context) pushRule(rule *Rule, begin Cursor) {
+	ctx.stack = append(ctx.stack, StackFrame{Rule: rule, Begin: begin})
+}
+
+func (ctx *Context) popRule() {
+	ctx.stack = ctx.stack[:len(ctx.stack)-1]
+}
+
+// failRule records the rule stack of a syntax error leaving the current rule
+// unless it was recorded by an inner rule already
+func (ctx *Context) failRule(err error) {
+	unexpErr, ok := err.(*ErrUnexpectedToken)
+	if !ok || unexpErr == ctx.errStackOf {
+		return
+	}
+	ctx.errStackOf = unexpErr
+	ctx.errStack = append(ctx.errStack[:0], ctx.stack...)
+}
+
+// attachStack attaches the recorded rule stack to the syntax error
+func (ctx *Context) attachStack(err error) {
+	unexpErr, ok := err.(*ErrUnexpectedToken)
+	if ok && unexpErr == ctx.errStackOf && unexpErr.Stack == nil {
+		unexpErr.Stack = append([]StackFrame(nil), ctx.errStack...)
+	}
+}
+
+func (ctx *Context) openScope() {
+	if ctx.deferActions {
+		ctx.journal = append(ctx.journal, journalEntry{op: opOpenScope})
+		return
+	}
+	ctx.scopes = append(ctx.scopes, len(ctx.symbols))
+}
+
+func (ctx *Context) closeScope() {
+	if ctx.deferActions {
+		ctx.journal = append(ctx.journal, journalEntry{op: opCloseScope})
+		return
+	}
+	ctx.symbols = ctx.symbols[:ctx.scopes[len(ctx.scopes)-1]]
+	ctx.scopes = ctx.scopes[:len(ctx.scopes)-1]
+}
+
+// action executes the action of the given rule immediately
+// or defers it until the parse is committed in deferred mode
+func (ctx *Context) action(rule *Rule, frag Fragment) error {
+	if ctx.deferActions {
+		if rule.Action != nil {
+			ctx.journal = append(ctx.journal, journalEntry{
+				op:    opAction,
+				rule:  rule,
+				frag:  frag,
+				stack: append([]StackFrame(nil), ctx.stack...),
+			})
+		}
+		return nil
+	}
+	if rule.Action != nil {
+		if err := rule.Action(ctx, frag); err != nil {
+			if err := ctx.actionErr(err, frag); err != nil {
+				return err
+			}
+		}
+	}
+	if rule.Undo != nil {
+		// Remember the executed action to be able to undo it
+		ctx.journal = append(ctx.journal, journalEntry{
+			op:   opAction,
+			rule: rule,
+			frag: frag,
+		})
+	}
+	return nil
+}
+
+// commit executes all deferred actions in the order they were matched in
+func (ctx *Context) commit() error {
+	if !ctx.deferActions {
+		return nil
+	}
+	journal, stack := ctx.journal, ctx.stack
+	ctx.journal, ctx.deferActions = nil, false
+	defer func() { ctx.stack, ctx.deferActions = stack, true }()
+
+	for _, entry := range journal {
+		switch entry.op {
+		case opOpenScope:
+			ctx.openScope()
+		case opCloseScope:
+			ctx.closeScope()
+		case opAction:
+			ctx.stack = entry.stack
+			err := entry.rule.Action(ctx, entry.frag)
+			if err != nil {
+				if err := ctx.actionErr(err, entry.frag); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// pushChoice enters a new choice point
+func (ctx *Context) pushChoice() {
+	ctx.choices = append(ctx.choices, 0)
+}
+
+// popChoice leaves the current choice point
+// returning true if a cut was passed
+func (ctx *Context) popChoice() (cut bool) {
+	cut = ctx.choices[len(ctx.choices)-1] > 0
+	ctx.choices = ctx.choices[:len(ctx.choices)-1]
+	return
+}
+
+// cut commits the current choice point
+func (ctx *Context) cut() {
+	if len(ctx.choices) > 0 {
+		ctx.choices[len(ctx.choices)-1]++
+	}
+}
+
+// cuts returns the number of cuts passed in the current choice point
+func (ctx *Context) cuts() uint {
+	if len(ctx.choices) < 1 {
+		return 0
+	}
+	return ctx.choices[len(ctx.choices)-1]
+}
+
+// committed returns true if a cut was passed in the current choice point
+func (ctx *Context) committed() bool {
+	return ctx.cuts() > 0
+}
+
+// checkpoint represents a backtracking position of the parser
+type checkpoint struct {
+	cr      Cursor
+	records int
+	values  int
+	mark    mark
+}
+
+// checkpoint returns the current backtracking position of the parser
+func (ctx *Context) checkpoint(scan *scanner) checkpoint {
+	return checkpoint{
+		cr:      scan.Lexer.cr,
+		records: len(scan.Records),
+		values:  len(scan.Values),
+		mark:    ctx.mark(),
+	}
+}
+
+// backtrack discards everything parsed after the checkpoint
+func (ctx *Context) backtrack(cp checkpoint, scan *scanner) {
+	scan.Reset(cp.cr, cp.records, cp.values)
+	ctx.rewind(cp.mark)
+}
+
+// mark returns the current backtracking position
+func (ctx *Context) mark() mark {
+	return mark{
+		symbols:     len(ctx.symbols),
+		journal:     len(ctx.journal),
+		diagnostics: len(ctx.diagnostics),
+	}
+}
+
+// rewind unwinds all changes made after the given backtracking position
+// undoing the actions executed in the meantime in reverse order
+func (ctx *Context) rewind(mk mark) {
+	if !ctx.deferActions {
+		for ix := len(ctx.journal) - 1; ix >= mk.journal; ix-- {
+			entry := ctx.journal[ix]
+			entry.rule.Undo(ctx, entry.frag)
+		}
+	}
+	ctx.journal = ctx.journal[:mk.journal]
+	ctx.symbols = ctx.symbols[:mk.symbols]
+	ctx.diagnostics = ctx.diagnostics[:mk.diagnostics]
+}
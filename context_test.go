@@ -0,0 +1,150 @@
+package parser_test
+
+import (
+	"errors"
+	"testing"
+
+	llp "github.com/romshark/llparser"
+	"github.com/stretchr/testify/require"
+)
+
+func TestContextValue(t *testing.T) {
+	type Model struct{ Words []string }
+
+	pr := newParser(t, &llp.Rule{
+		Designation: "words",
+		Pattern: &llp.Repeated{
+			Min: 1,
+			Pattern: llp.Either{
+				termSeparator,
+				&llp.Rule{
+					Designation: "word",
+					Pattern:     termLatinWord,
+					Action: func(ctx *llp.Context, f llp.Fragment) error {
+						mod := ctx.Value().(*Model)
+						mod.Words = append(mod.Words, string(f.Src()))
+						return nil
+					},
+				},
+			},
+		},
+	}, nil)
+
+	// Reuse the same grammar for different models
+	for src, expected := range map[string][]string{
+		"foo,bar": {"foo", "bar"},
+		"baz":     {"baz"},
+	} {
+		mod := &Model{}
+		result, err := pr.ParseWith(
+			newSource(src),
+			llp.ParseOptions{Value: mod},
+		)
+		require.NoError(t, err)
+		require.NotNil(t, result)
+		require.NotNil(t, result.Fragment)
+		require.Equal(t, src, string(result.Fragment.Src()))
+		require.Equal(t, expected, mod.Words)
+	}
+}
+
+func TestContextStack(t *testing.T) {
+	var stack []llp.StackFrame
+	inner := &llp.Rule{
+		Designation: "inner",
+		Pattern:     testR_bar,
+		Action: func(ctx *llp.Context, f llp.Fragment) error {
+			stack = append([]llp.StackFrame{}, ctx.Stack()...)
+			return nil
+		},
+	}
+	main := &llp.Rule{
+		Designation: "main",
+		Pattern:     llp.Sequence{testR_foo, inner},
+	}
+	pr := newParser(t, main, nil)
+
+	src := newSource("foobar")
+	_, err := pr.Parse(src)
+	require.NoError(t, err)
+
+	require.Len(t, stack, 2)
+	require.Equal(t, main, stack[0].Rule)
+	CheckCursor(t, src, stack[0].Begin, 1, 1)
+	require.Equal(t, inner, stack[1].Rule)
+	CheckCursor(t, src, stack[1].Begin, 1, 4)
+}
+
+func TestContextSymbols(t *testing.T) {
+	pr := newParser(t, newSymbolGrammar(), nil)
+
+	t.Run("Declared", func(t *testing.T) {
+		_, err := pr.Parse(newSource("$a;a;{a;$b;b;}"))
+		require.NoError(t, err)
+	})
+
+	t.Run("Undeclared", func(t *testing.T) {
+		_, err := pr.Parse(newSource("$a;b;"))
+		require.Error(t, err)
+	})
+
+	t.Run("Redeclared", func(t *testing.T) {
+		_, err := pr.Parse(newSource("$a;$a;"))
+		require.Error(t, err)
+		require.IsType(t, &llp.Err{}, err)
+		require.Equal(t, `"a" redeclared at test.txt:1:4`, err.Error())
+	})
+
+	t.Run("Shadowing", func(t *testing.T) {
+		_, err := pr.Parse(newSource("$a;{$a;a;}a;"))
+		require.NoError(t, err)
+	})
+
+	t.Run("ScopeClosed", func(t *testing.T) {
+		_, err := pr.Parse(newSource("{$a;}a;"))
+		require.Error(t, err)
+	})
+
+	t.Run("UnwoundOnBacktracking", func(t *testing.T) {
+		// The first declaration option fails at "!"
+		// and must not leave "a" declared
+		_, err := pr.Parse(newSource("$a!a;"))
+		require.NoError(t, err)
+	})
+}
+
+func TestContextPredicate(t *testing.T) {
+	pr := newParser(t, &llp.Rule{
+		Designation: "main",
+		Pattern: llp.Sequence{
+			&llp.Predicate{
+				Designation: "user approval",
+				Fn: func(ctx *llp.Context, _ llp.Cursor) bool {
+					return ctx.Value().(bool)
+				},
+			},
+			testR_foo,
+		},
+	}, nil)
+
+	result, err := pr.ParseWith(
+		newSource("foo"),
+		llp.ParseOptions{Value: true},
+	)
+	require.NoError(t, err)
+	require.NotNil(t, result)
+
+	result, err = pr.ParseWith(
+		newSource("foo"),
+		llp.ParseOptions{Value: false},
+	)
+	require.Error(t, err)
+	require.Nil(t, result)
+	var unexpErr *llp.ErrUnexpectedToken
+	require.True(t, errors.As(err, &unexpErr))
+	require.Equal(
+		t,
+		"unexpected token, expected {user approval} at test.txt:1:1",
+		err.Error(),
+	)
+}
@@ -1,11 +1,33 @@
 package parser
 
-import "fmt"
+import (
+	"fmt"
+	"sync/atomic"
+)
 
-// SourceFile represents a source file
+// SourceFile represents a source file.
+// The source code must not be modified once the file
+// has been parsed or its lines have been accessed
 type SourceFile struct {
 	Name string
 	Src  []rune
+
+	// TabWidth defines the width of tabs used by DisplayColumn.
+	// DefaultTabWidth is used when it's 0
+	TabWidth uint
+
+	// LineEndings defines the recognized line-breaks.
+	// The cursors of parse-trees refer to a copy of the file
+	// if it differs from the line endings of the parser
+	LineEndings LineEndings
+
+	// LineBreaks holds the original line-breaks of a file
+	// created by NormalizeLineEndings
+	LineBreaks []string
+
+	// lines holds the *lineIndex built on first access.
+	// Copies of the file share the index built so far
+	lines atomic.Value
 }
 
 // Cursor represents a source-code location
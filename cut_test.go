@@ -0,0 +1,169 @@
+package parser_test
+
+import (
+	"testing"
+
+	llp "github.com/romshark/llparser"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCutEither(t *testing.T) {
+	t.Run("WithoutCut", func(t *testing.T) {
+		pr := newParser(t, newCutGrammar(false), nil)
+		mainFrag, err := pr.Parse(newSource("foo,func bar(,"))
+		require.Error(t, err)
+		require.Equal(t, "unexpected token at test.txt:1:5", err.Error())
+		require.Nil(t, mainFrag)
+	})
+
+	t.Run("WithCut", func(t *testing.T) {
+		pr := newParser(t, newCutGrammar(true), nil)
+		mainFrag, err := pr.Parse(newSource("foo,func bar(,"))
+		require.Error(t, err)
+		require.Equal(
+			t,
+			"unexpected token, expected {'()'} at test.txt:1:13",
+			err.Error(),
+		)
+		require.Nil(t, mainFrag)
+	})
+
+	t.Run("Match", func(t *testing.T) {
+		pr := newParser(t, newCutGrammar(true), nil)
+		src := newSource("foo,func bar(),")
+		mainFrag, err := pr.Parse(src)
+		require.NoError(t, err)
+		checkFrag(t, src, mainFrag, 0, C{1, 1}, C{1, 16}, 4)
+	})
+}
+
+func TestCutRepeated(t *testing.T) {
+	newGrammar := func(cut llp.Pattern) *llp.Rule {
+		item := llp.Sequence{
+			&llp.Exact{Expectation: []rune("[")},
+			termLatinWord,
+			&llp.Exact{Expectation: []rune("]")},
+		}
+		if cut != nil {
+			item = llp.Sequence{item[0], cut, item[1], item[2]}
+		}
+		return &llp.Rule{
+			Designation: "list",
+			Pattern: llp.Sequence{
+				&llp.Repeated{Pattern: item},
+				&llp.Repeated{Min: 0, Max: 1, Pattern: termLatinWord},
+			},
+		}
+	}
+
+	t.Run("WithoutCut", func(t *testing.T) {
+		pr := newParser(t, newGrammar(nil), nil)
+		mainFrag, err := pr.Parse(newSource("[a][b)"))
+		require.Error(t, err)
+		require.Equal(t, "unexpected token at test.txt:1:4", err.Error())
+		require.Nil(t, mainFrag)
+	})
+
+	t.Run("WithCut", func(t *testing.T) {
+		pr := newParser(t, newGrammar(llp.Cut{}), nil)
+		mainFrag, err := pr.Parse(newSource("[a][b)"))
+		require.Error(t, err)
+		require.Equal(
+			t,
+			"unexpected token, expected {']'} at test.txt:1:6",
+			err.Error(),
+		)
+		require.Nil(t, mainFrag)
+	})
+
+	t.Run("WithCutEOF", func(t *testing.T) {
+		pr := newParser(t, newGrammar(llp.Cut{}), nil)
+		mainFrag, err := pr.Parse(newSource("[a][b"))
+		require.Error(t, err)
+		require.IsType(t, &llp.ErrUnexpectedToken{}, err)
+		require.Equal(t, uint(5), err.(*llp.ErrUnexpectedToken).At.Index)
+		require.Nil(t, mainFrag)
+	})
+
+	t.Run("Match", func(t *testing.T) {
+		pr := newParser(t, newGrammar(llp.Cut{}), nil)
+		src := newSource("[a][b]c")
+		mainFrag, err := pr.Parse(src)
+		require.NoError(t, err)
+		checkFrag(t, src, mainFrag, 0, C{1, 1}, C{1, 8}, 7)
+	})
+}
+
+func TestCutNot(t *testing.T) {
+	// A cut inside a negation must not commit the enclosing either
+	pr := newParser(t, &llp.Rule{
+		Designation: "main",
+		Pattern: llp.Either{
+			llp.Sequence{
+				llp.Not{Pattern: llp.Sequence{testR_bar, llp.Cut{}, testR_bar}},
+				testR_foo,
+				testR_foo,
+			},
+			llp.Sequence{testR_foo, testR_bar},
+		},
+	}, nil)
+
+	src := newSource("foobar")
+	mainFrag, err := pr.Parse(src)
+	require.NoError(t, err)
+	checkFrag(t, src, mainFrag, 0, C{1, 1}, C{1, 7}, 2)
+}
+
+func TestCutReparse(t *testing.T) {
+	calls := 0
+	keyword := &llp.Rule{
+		Designation: "keyword",
+		Pattern: llp.Sequence{
+			&llp.Lexed{
+				Designation: "func",
+				MinLen:      4,
+				Fn: func(ix uint, cr llp.Cursor) bool {
+					calls++
+					return ix < 4 && cr.File.Src[cr.Index] == rune("func"[ix])
+				},
+			},
+			// The cut commits the either enclosing the keyword
+			llp.Cut{},
+		},
+	}
+	grammar := &llp.Rule{
+		Designation: "statements",
+		Pattern: &llp.Repeated{
+			Pattern: llp.Sequence{
+				llp.Either{
+					llp.Sequence{
+						keyword,
+						termSpace,
+						termLatinWord,
+						&llp.Exact{Expectation: []rune("()")},
+					},
+					&llp.Rule{
+						Designation: "expression",
+						Pattern:     termLatinWord,
+					},
+				},
+				termSeparator,
+			},
+		},
+	}
+	pr := newParser(t, grammar, nil)
+	pr.Incremental = true
+	tree, err := pr.Parse(newSource("func a(),func b(),c,"))
+	require.NoError(t, err)
+
+	// The reused keywords still commit the either
+	calls = 0
+	_, err = pr.Reparse(tree, []llp.Edit{{Begin: 16, End: 17}})
+	require.Error(t, err)
+	require.Zero(t, calls)
+	_, expected := newParser(t, grammar, nil).Parse(
+		newSource("func a(),func b(,c,"),
+	)
+	require.Error(t, expected)
+	require.Equal(t, expected.Error(), err.Error())
+}
@@ -1,5 +1,10 @@
 package parser
 
+import (
+	"fmt"
+	"strings"
+)
+
 // DebugLogEntry represents a debug log entry
 type DebugLogEntry struct {
 	Pattern Pattern
@@ -11,6 +16,60 @@ type DebugLogEntry struct {
 // DebugProfile represents a debug-profile generated by Parser.Debug
 type DebugProfile struct {
 	Log []*DebugLogEntry
+
+	// Kinds defines the kind names used by String
+	Kinds *KindRegistry
+}
+
+// String stringifies the log as a tree of the patterns, their kinds
+// and whether they were matched:
+//
+//	1:1 rule main (list) matched
+//	. 1:1 exact 'a' (0) mismatched
+func (dp *DebugProfile) String() string {
+	var b strings.Builder
+	for _, ent := range dp.Log {
+		b.WriteString(strings.Repeat(". ", int(ent.Level)))
+		b.WriteString(ent.At.String())
+		b.WriteString(" ")
+		b.WriteString(dp.describe(ent.Pattern))
+		if ent.Matched {
+			b.WriteString(" matched\n")
+		} else {
+			b.WriteString(" mismatched\n")
+		}
+	}
+	return b.String()
+}
+
+// describe returns the type and designation of the pattern
+// followed by its kind name if any
+func (dp *DebugProfile) describe(pattern Pattern) string {
+	switch pt := pattern.(type) {
+	case *Rule:
+		return dp.kindOf("rule", pt.Designation, pt.Kind)
+	case *Exact:
+		return dp.kindOf("exact", pt.Desig(), pt.Kind)
+	case *Lexed:
+		return dp.kindOf("lexed", pt.Designation, pt.Kind)
+	case Sequence:
+		return "sequence"
+	case Either:
+		return "either"
+	case *Repeated:
+		return "repeated"
+	case Not:
+		return "not"
+	case Label:
+		return "label " + pt.Name
+	case Shaped:
+		return "shaped"
+	case *Predicate:
+		return "predicate " + pt.Designation
+	case Cut:
+		return "cut"
+	}
+	return pattern.Desig()
 }
 
 func newDebugProfile() *DebugProfile {
@@ -43,3 +102,14 @@ func (dp *DebugProfile) markMismatch(index int) {
 	}
 	dp.Log[index].Matched = false
 }
+
+func (dp *DebugProfile) kindOf(
+	typ string,
+	designation string,
+	kind FragmentKind,
+) string {
+	if designation != "" {
+		typ += " " + designation
+	}
+	return fmt.Sprintf("%s (%s)", typ, dp.Kinds.nameOf(kind))
+}
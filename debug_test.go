@@ -32,6 +32,12 @@ func stringifyPattern(pt llp.Pattern) string {
 		)
 	case llp.Not:
 		return fmt.Sprintf("not <- %s", stringifyPattern(tp.Pattern))
+	case llp.Shaped:
+		return fmt.Sprintf(
+			"shaped (%s) <- %s",
+			tp.Shape,
+			stringifyPattern(tp.Pattern),
+		)
 	case *llp.Repeated:
 		return fmt.Sprintf(
 			"repeated (min: %d, max: %d) <- %s",
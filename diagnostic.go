@@ -0,0 +1,477 @@
+package parser
+
+import (
+	"errors"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Severity defines the severity of a diagnostic
+type Severity int
+
+const (
+	// SeverityError represents errors aborting the parse
+	SeverityError Severity = iota
+
+	// SeverityWarning represents warnings
+	SeverityWarning
+
+	// SeverityInfo represents informational diagnostics
+	SeverityInfo
+
+	// SeverityHint represents hints
+	SeverityHint
+)
+
+// String stringifies the severity
+func (sv Severity) String() string {
+	switch sv {
+	case SeverityError:
+		return "error"
+	case SeverityWarning:
+		return "warning"
+	case SeverityInfo:
+		return "info"
+	case SeverityHint:
+		return "hint"
+	}
+	return "severity(" + strconv.Itoa(int(sv)) + ")"
+}
+
+// Stable codes of the diagnostics of parser errors
+const (
+	// CodeError is the code of errors without a specific code.
+	// It's omitted when rendering diagnostics
+	CodeError = "error"
+
+	// CodeUnexpectedToken is the code of ErrUnexpectedToken
+	CodeUnexpectedToken = "unexpected-token"
+
+	// CodeRecursionLimit is the code of errors caused by exceeding
+	// Parser.MaxRecursionLevel
+	CodeRecursionLimit = "recursion-limit"
+
+	// CodeAction is the code of errors returned by rule actions
+	CodeAction = "action"
+
+	// CodeReduce is the code of errors returned by rule reducers
+	CodeReduce = "reduce"
+
+	// CodeMissing is the code of diagnostics of expected but absent
+	// fragments inserted by tolerant parses
+	CodeMissing = "missing"
+)
+
+// Diagnostic represents a problem in the source code.
+// Actions may return diagnostics as errors. Diagnostics of a severity
+// other than SeverityError don't abort the parse and are collected
+// in Result.Diagnostics instead (see also Context.Report)
+type Diagnostic struct {
+	Severity Severity
+
+	// Code optionally identifies the kind of problem
+	Code string
+
+	// Message describes the problem
+	Message string
+
+	// Begin and End define the range of the primary location.
+	// Diagnostics returned by actions without a location
+	// are located at the fragment of the action
+	Begin Cursor
+	End   Cursor
+
+	// Label optionally annotates the primary location
+	Label string
+
+	// Labels defines related locations
+	Labels []DiagnosticLabel
+
+	// Notes defines additional notes rendered below the source code
+	Notes []string
+
+	// Fixes defines suggested fixes
+	Fixes []Fix
+}
+
+// Error implements the error interface
+func (diag *Diagnostic) Error() string {
+	if diag.Begin.Line == 0 {
+		return diag.Message
+	}
+	return diag.Message + " at " + diag.Begin.String()
+}
+
+// Fix represents a suggested fix of a diagnostic
+type Fix struct {
+	// Message describes the fix
+	Message string
+
+	// Edits defines the edits of the source code applying the fix
+	Edits []Edit
+}
+
+// DiagnosticLabel represents an annotated range of the source code
+type DiagnosticLabel struct {
+	Begin   Cursor
+	End     Cursor
+	Message string
+}
+
+// RenderOptions defines the diagnostic rendering options
+type RenderOptions struct {
+	// Out defines the output and defaults to stdout
+	Out io.Writer
+
+	// Color enables ANSI colors
+	Color bool
+
+	// ContextLines defines the number of source lines rendered
+	// before and after the annotated lines
+	ContextLines uint
+}
+
+// ANSI escape sequences of the color rendering mode
+const (
+	ansiReset     = "\x1b[0m"
+	ansiBold      = "\x1b[1m"
+	ansiError     = "\x1b[1;31m"
+	ansiWarning   = "\x1b[1;33m"
+	ansiSecondary = "\x1b[1;34m"
+	ansiGutter    = "\x1b[1;34m"
+)
+
+// annotation represents an annotated range of a rendered diagnostic
+type annotation struct {
+	begin   Cursor
+	end     Cursor
+	label   string
+	primary bool
+}
+
+// renderer renders diagnostics to the output stopping at the first error
+type renderer struct {
+	out     io.Writer
+	options RenderOptions
+	err     error
+}
+
+func (r *renderer) write(strs ...string) {
+	for _, str := range strs {
+		if r.err != nil {
+			return
+		}
+		_, r.err = io.WriteString(r.out, str)
+	}
+}
+
+// style returns the string wrapped in the escape sequence
+// if colors are enabled
+func (r *renderer) style(escape, str string) string {
+	if !r.options.Color || str == "" {
+		return str
+	}
+	return escape + str + ansiReset
+}
+
+// validEnd returns the end of a range or its beginning
+// if the end is unset or precedes the beginning
+func validEnd(begin, end Cursor) Cursor {
+	if end.Line == 0 || end.Index < begin.Index {
+		return begin
+	}
+	return end
+}
+
+// RenderDiagnostic renders the diagnostic followed by the source code of
+// the annotated locations with the ranges underlined:
+//
+//	error: unexpected token
+//	 --> main.txt:2:5
+//	  |
+//	2 | let 1x = 2
+//	  |     ^^ expected identifier
+//	  |
+//	  = note: identifiers begin with a letter
+//
+// Ranges without a valid end are rendered as empty ranges at their beginning
+func RenderDiagnostic(diag Diagnostic, options RenderOptions) error {
+	if options.Out == nil {
+		// Use stdout by default
+		options.Out = os.Stdout
+	}
+	diag.End = validEnd(diag.Begin, diag.End)
+	labels := make([]DiagnosticLabel, len(diag.Labels))
+	for ix, lb := range diag.Labels {
+		lb.End = validEnd(lb.Begin, lb.End)
+		labels[ix] = lb
+	}
+	diag.Labels = labels
+	r := &renderer{out: options.Out, options: options}
+	header, escape := diag.Severity.String(), ansiError
+	if diag.Severity != SeverityError {
+		escape = ansiWarning
+	}
+	if diag.Code != "" && diag.Code != CodeError {
+		header += "[" + diag.Code + "]"
+	}
+	r.write(
+		r.style(escape, header),
+		r.style(ansiBold, ": "+diag.Message), "\n",
+	)
+	if diag.Begin.Line == 0 {
+		// Diagnostics without a location consist of the message only
+		return r.err
+	}
+
+	// Group the spans by file in order of appearance
+	spans := []annotation{{
+		begin:   diag.Begin,
+		end:     diag.End,
+		label:   diag.Label,
+		primary: true,
+	}}
+	for _, lb := range diag.Labels {
+		spans = append(spans, annotation{
+			begin: lb.Begin,
+			end:   lb.End,
+			label: lb.Message,
+		})
+	}
+	var files []*SourceFile
+	byFile := map[*SourceFile][]annotation{}
+	for _, sp := range spans {
+		if _, ok := byFile[sp.begin.File]; !ok {
+			files = append(files, sp.begin.File)
+		}
+		byFile[sp.begin.File] = append(byFile[sp.begin.File], sp)
+	}
+
+	// The gutter is as wide as the greatest line number
+	width := 0
+	for _, sp := range spans {
+		last := sp.end.Line + options.ContextLines
+		if sp.end.File != nil && last > sp.end.File.LineCount() {
+			last = sp.end.File.LineCount()
+		}
+		if w := len(strconv.FormatUint(uint64(last), 10)); w > width {
+			width = w
+		}
+	}
+	gutter := strings.Repeat(" ", width)
+
+	for ix, file := range files {
+		arrow := "-->"
+		if ix > 0 {
+			arrow = ":::"
+		}
+		r.write(
+			gutter, r.style(ansiGutter, arrow), " ",
+			byFile[file][0].begin.String(), "\n",
+		)
+		if file != nil {
+			r.snippet(file, byFile[file], gutter)
+		}
+	}
+
+	for _, note := range diag.Notes {
+		r.write(gutter, " ", r.style(ansiGutter, "="), " ")
+		r.write(r.style(ansiBold, "note"), ": ", note, "\n")
+	}
+	for _, fix := range diag.Fixes {
+		r.write(gutter, " ", r.style(ansiGutter, "="), " ")
+		r.write(r.style(ansiBold, "help"), ": ", fix.Message, "\n")
+	}
+	return r.err
+}
+
+// snippet renders the annotated lines of the file and their context
+func (r *renderer) snippet(file *SourceFile, spans []annotation, gutter string) {
+	// Determine the rendered lines
+	lines := map[uint]bool{}
+	context := r.options.ContextLines
+	for _, sp := range spans {
+		first := uint(1)
+		if sp.begin.Line > context {
+			first = sp.begin.Line - context
+		}
+		for ln := first; ln <= sp.end.Line+context; ln++ {
+			if ln <= file.LineCount() {
+				lines[ln] = true
+			}
+		}
+	}
+	sorted := make([]uint, 0, len(lines))
+	for ln := range lines {
+		sorted = append(sorted, ln)
+	}
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	bar := r.style(ansiGutter, "|")
+	r.write(gutter, " ", bar, "\n")
+	for ix, ln := range sorted {
+		if ix > 0 && sorted[ix-1] != ln-1 {
+			// Skip the lines in between
+			r.write(r.style(ansiGutter, "..."), "\n")
+		}
+		num := strconv.FormatUint(uint64(ln), 10)
+		text, _ := file.Line(ln)
+		r.write(
+			r.style(ansiGutter, gutter[len(num):]+num+" |"), " ",
+			expandTabs(file, text), "\n",
+		)
+		for _, sp := range spans {
+			if ln < sp.begin.Line || ln > sp.end.Line {
+				continue
+			}
+			r.underline(file, ln, sp, gutter)
+		}
+	}
+	r.write(gutter, " ", bar, "\n")
+}
+
+// underline renders the underline of the span on the given line.
+// The label is rendered on the last line of the span
+func (r *renderer) underline(
+	file *SourceFile,
+	line uint,
+	sp annotation,
+	gutter string,
+) {
+	lineBegin, _ := file.Offset(line, 1)
+	text, _ := file.Line(line)
+	lineEnd := lineBegin + uint(len(text))
+	begin, end := lineBegin, lineEnd
+	if line == sp.begin.Line {
+		begin = sp.begin.Index
+	}
+	if line == sp.end.Line {
+		end = sp.end.Index
+	}
+
+	// Clamp the range of user-defined diagnostics to the line
+	if begin < lineBegin {
+		begin = lineBegin
+	} else if begin > lineEnd {
+		begin = lineEnd
+	}
+	if end < begin {
+		end = begin
+	} else if end > lineEnd {
+		end = lineEnd
+	}
+
+	from, err := file.DisplayColumn(begin)
+	if err != nil || from < 1 {
+		from = 1
+	}
+	to, err := file.DisplayColumn(end)
+	if err != nil || to < from {
+		to = from
+	}
+	length := int(to - from)
+	if length < 1 {
+		// Empty ranges point at the following rune
+		length = 1
+	}
+
+	mark, escape := "-", ansiSecondary
+	if sp.primary {
+		mark, escape = "^", ansiError
+	}
+	marks := strings.Repeat(mark, length)
+	if line == sp.end.Line && sp.label != "" {
+		marks += " " + sp.label
+	}
+	r.write(
+		gutter, " ", r.style(ansiGutter, "|"), " ",
+		strings.Repeat(" ", int(from-1)), r.style(escape, marks), "\n",
+	)
+}
+
+// expandTabs replaces tabs by spaces up to the next tab stop
+func expandTabs(file *SourceFile, text []rune) string {
+	width := file.TabWidth
+	if width < 1 {
+		width = DefaultTabWidth
+	}
+	var b strings.Builder
+	column := uint(0)
+	for _, rn := range text {
+		if rn == '\t' {
+			next := column + width - column%width
+			b.WriteString(strings.Repeat(" ", int(next-column)))
+			column = next
+			continue
+		}
+		b.WriteRune(rn)
+		column++
+	}
+	return b.String()
+}
+
+// ToDiagnostic converts the error to a diagnostic. Diagnostics are
+// returned as is while errors without a location are converted
+// to diagnostics without a location
+func ToDiagnostic(err error) *Diagnostic {
+	var diag *Diagnostic
+	if errors.As(err, &diag) {
+		return diag
+	}
+	var unexpected *ErrUnexpectedToken
+	if errors.As(err, &unexpected) {
+		diag := &Diagnostic{
+			Code:    CodeUnexpectedToken,
+			Message: "unexpected token",
+			Begin:   unexpected.At,
+			End:     unexpected.At,
+		}
+		if file := unexpected.At.File; file != nil &&
+			unexpected.At.Index < uint(len(file.Src)) {
+			// Underline the unexpected rune
+			diag.End = file.cursor(unexpected.At.Index + 1)
+		}
+		if unexpected.Expected != nil {
+			diag.Label = "expected " +
+				designation(unexpected.Expected, unexpected.kinds)
+		}
+		for ix, sg := range unexpected.Suggestions {
+			if ix == 0 {
+				// Underline the replaced source code
+				diag.End = sg.End
+			}
+			diag.Fixes = append(diag.Fixes, Fix{
+				Message: "did you mean '" + sg.Text + "'?",
+				Edits: []Edit{{
+					Begin: sg.Begin.Index,
+					End:   sg.End.Index,
+					Text:  sg.Text,
+				}},
+			})
+		}
+		return diag
+	}
+	var generic *Err
+	if errors.As(err, &generic) {
+		code := generic.Code
+		if code == "" {
+			code = CodeError
+		}
+		return &Diagnostic{
+			Code:    code,
+			Message: generic.Err.Error(),
+			Begin:   generic.At,
+			End:     generic.At,
+		}
+	}
+	return &Diagnostic{Code: CodeError, Message: err.Error()}
+}
+
+// RenderError renders the error as a diagnostic (see RenderDiagnostic).
+// Errors without a location are rendered as a message only
+func RenderError(err error, options RenderOptions) error {
+	return RenderDiagnostic(*ToDiagnostic(err), options)
+}
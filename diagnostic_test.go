@@ -0,0 +1,298 @@
+package parser_test
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	llp "github.com/romshark/llparser"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRenderDiagnostic(t *testing.T) {
+	src := newSource("fn f {\n\tx = 1\n\ty = 2\n}\n")
+	at := func(offset uint) llp.Cursor {
+		cr, err := src.CursorAt(offset)
+		require.NoError(t, err)
+		return cr
+	}
+
+	for name, test := range map[string]struct {
+		Diag     llp.Diagnostic
+		Options  llp.RenderOptions
+		Expected string
+	}{
+		"Caret": {
+			llp.Diagnostic{
+				Message: "undefined variable",
+				Begin:   at(8),
+				End:     at(9),
+				Label:   "not declared",
+				Notes:   []string{"declare it first"},
+			},
+			llp.RenderOptions{},
+			"error: undefined variable\n" +
+				" --> test.txt:2:2\n" +
+				"  |\n" +
+				"2 |     x = 1\n" +
+				"  |     ^ not declared\n" +
+				"  |\n" +
+				"  = note: declare it first\n",
+		},
+		"Labels and context": {
+			llp.Diagnostic{
+				Message: "duplicate assignment",
+				Begin:   at(15),
+				End:     at(20),
+				Labels: []llp.DiagnosticLabel{
+					{Begin: at(8), End: at(13), Message: "first here"},
+				},
+			},
+			llp.RenderOptions{ContextLines: 1},
+			"error: duplicate assignment\n" +
+				" --> test.txt:3:2\n" +
+				"  |\n" +
+				"1 | fn f {\n" +
+				"2 |     x = 1\n" +
+				"  |     ----- first here\n" +
+				"3 |     y = 2\n" +
+				"  |     ^^^^^\n" +
+				"4 | }\n" +
+				"  |\n",
+		},
+		"Multiline": {
+			llp.Diagnostic{
+				Message: "unused block",
+				Begin:   at(5),
+				End:     at(21),
+				Label:   "block",
+			},
+			llp.RenderOptions{},
+			"error: unused block\n" +
+				" --> test.txt:1:6\n" +
+				"  |\n" +
+				"1 | fn f {\n" +
+				"  |      ^\n" +
+				"2 |     x = 1\n" +
+				"  | ^^^^^^^^^\n" +
+				"3 |     y = 2\n" +
+				"  | ^^^^^^^^^\n" +
+				"4 | }\n" +
+				"  | ^ block\n" +
+				"  |\n",
+		},
+		"Out of range": {
+			// The indexes of user-defined cursors may lie outside
+			// of their lines and even outside of the file
+			llp.Diagnostic{
+				Message: "bad",
+				Begin:   llp.Cursor{Index: 0, Line: 2, Column: 1, File: src},
+				End:     llp.Cursor{Index: 100, Line: 2, Column: 9, File: src},
+			},
+			llp.RenderOptions{},
+			"error: bad\n" +
+				" --> test.txt:2:1\n" +
+				"  |\n" +
+				"2 |     x = 1\n" +
+				"  | ^^^^^^^^^\n" +
+				"  |\n",
+		},
+		"Outside of file": {
+			llp.Diagnostic{
+				Message: "bad",
+				Begin:   llp.Cursor{Index: 100, Line: 2, Column: 9, File: src},
+				End:     llp.Cursor{Index: 100, Line: 2, Column: 9, File: src},
+			},
+			llp.RenderOptions{},
+			"error: bad\n" +
+				" --> test.txt:2:9\n" +
+				"  |\n" +
+				"2 |     x = 1\n" +
+				"  |          ^\n" +
+				"  |\n",
+		},
+		"No end": {
+			llp.Diagnostic{
+				Message: "bad",
+				Begin:   at(8),
+				Labels: []llp.DiagnosticLabel{
+					{Begin: at(3), End: at(1), Message: "reversed"},
+				},
+			},
+			llp.RenderOptions{},
+			"error: bad\n" +
+				" --> test.txt:2:2\n" +
+				"  |\n" +
+				"1 | fn f {\n" +
+				"  |    - reversed\n" +
+				"2 |     x = 1\n" +
+				"  |     ^\n" +
+				"  |\n",
+		},
+		"Color": {
+			llp.Diagnostic{Message: "bad", Begin: at(0), End: at(2)},
+			llp.RenderOptions{Color: true},
+			"\x1b[1;31merror\x1b[0m\x1b[1m: bad\x1b[0m\n" +
+				" \x1b[1;34m-->\x1b[0m test.txt:1:1\n" +
+				"  \x1b[1;34m|\x1b[0m\n" +
+				"\x1b[1;34m1 |\x1b[0m fn f {\n" +
+				"  \x1b[1;34m|\x1b[0m \x1b[1;31m^^\x1b[0m\n" +
+				"  \x1b[1;34m|\x1b[0m\n",
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			var buf bytes.Buffer
+			test.Options.Out = &buf
+			require.NoError(t, llp.RenderDiagnostic(test.Diag, test.Options))
+			require.Equal(t, test.Expected, buf.String())
+		})
+	}
+}
+
+func TestRenderError(t *testing.T) {
+	pr := newParser(t, newPairGrammar(), nil)
+	pr.Kinds = newKinds(t)
+	_, err := pr.Parse(newSource("a,;"))
+	require.Error(t, err)
+
+	var buf bytes.Buffer
+	require.NoError(t, llp.RenderError(err, llp.RenderOptions{Out: &buf}))
+	require.Equal(t, "error[unexpected-token]: unexpected token\n"+
+		" --> test.txt:1:3\n"+
+		"  |\n"+
+		"1 | a,;\n"+
+		"  |   ^ expected word\n"+
+		"  |\n",
+		buf.String(),
+	)
+
+	buf.Reset()
+	require.NoError(t, llp.RenderError(
+		errors.New("missing grammar"),
+		llp.RenderOptions{Out: &buf},
+	))
+	require.Equal(t, "error: missing grammar\n", buf.String())
+}
+
+func TestDiagnosticAction(t *testing.T) {
+	// grammar creates a grammar of "a" followed by either "!" or "?"
+	// where the action of "a" returns the given diagnostic
+	grammar := func(diag *llp.Diagnostic) *llp.Rule {
+		a := &llp.Rule{
+			Designation: "a",
+			Kind:        FrWord,
+			Pattern:     &llp.Exact{Expectation: []rune("a")},
+			Action: func(*llp.Context, llp.Fragment) error {
+				copied := *diag
+				return &copied
+			},
+		}
+		return &llp.Rule{
+			Designation: "main",
+			Pattern: llp.Either{
+				llp.Sequence{a, &llp.Exact{Expectation: []rune("!")}},
+				llp.Sequence{a, &llp.Exact{Expectation: []rune("?")}},
+			},
+		}
+	}
+
+	t.Run("Warnings", func(t *testing.T) {
+		for _, deferActions := range []bool{false, true} {
+			pr := newParser(t, grammar(&llp.Diagnostic{
+				Severity: llp.SeverityWarning,
+				Code:     "deprecated",
+				Message:  "a is deprecated",
+			}), nil)
+			pr.DeferActions = deferActions
+
+			// Warnings of backtracked matches are discarded
+			result, err := pr.ParseWith(newSource("a?"), llp.ParseOptions{})
+			require.NoError(t, err)
+			require.Len(t, result.Diagnostics, 1)
+			diag := result.Diagnostics[0]
+			require.Equal(t, llp.SeverityWarning, diag.Severity)
+			require.Equal(t, "deprecated", diag.Code)
+			CheckCursor(t, diag.Begin.File, diag.Begin, 1, 1)
+			CheckCursor(t, diag.End.File, diag.End, 1, 2)
+		}
+	})
+
+	t.Run("Err", func(t *testing.T) {
+		src := newSource("a!")
+		pr := newParser(t, grammar(&llp.Diagnostic{
+			Code:    "forbidden",
+			Message: "a is forbidden",
+			Fixes: []llp.Fix{{
+				Message: "replace by b",
+				Edits:   []llp.Edit{{Begin: 0, End: 1, Text: "b"}},
+			}},
+		}), nil)
+		_, err := pr.Parse(src)
+		require.Error(t, err)
+		require.IsType(t, &llp.Diagnostic{}, err)
+		require.Equal(t, "a is forbidden at test.txt:1:1", err.Error())
+		require.Equal(t, err, llp.ToDiagnostic(err))
+
+		var buf bytes.Buffer
+		require.NoError(t, llp.RenderError(err, llp.RenderOptions{Out: &buf}))
+		require.Equal(t, "error[forbidden]: a is forbidden\n"+
+			" --> test.txt:1:1\n"+
+			"  |\n"+
+			"1 | a!\n"+
+			"  | ^\n"+
+			"  |\n"+
+			"  = help: replace by b\n",
+			buf.String(),
+		)
+	})
+}
+
+func TestToDiagnostic(t *testing.T) {
+	src := newSource("ab")
+	for name, test := range map[string]struct {
+		Err      error
+		Expected llp.Diagnostic
+	}{
+		"Unexpected token": {
+			&llp.ErrUnexpectedToken{At: llp.NewCursor(src)},
+			llp.Diagnostic{
+				Code:    llp.CodeUnexpectedToken,
+				Message: "unexpected token",
+				Begin:   llp.NewCursor(src),
+				End:     llp.Cursor{Index: 1, Line: 1, Column: 2, File: src},
+			},
+		},
+		"Err": {
+			&llp.Err{
+				Err:  errors.New("too deep"),
+				At:   llp.NewCursor(src),
+				Code: llp.CodeRecursionLimit,
+			},
+			llp.Diagnostic{
+				Code:    llp.CodeRecursionLimit,
+				Message: "too deep",
+				Begin:   llp.NewCursor(src),
+				End:     llp.NewCursor(src),
+			},
+		},
+		"Plain": {
+			errors.New("missing grammar"),
+			llp.Diagnostic{Code: llp.CodeError, Message: "missing grammar"},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			require.Equal(t, test.Expected, *llp.ToDiagnostic(test.Err))
+		})
+	}
+
+	// Errors returned by actions are coded
+	pr := newParser(t, &llp.Rule{
+		Pattern: &llp.Exact{Expectation: []rune("a")},
+		Action: func(*llp.Context, llp.Fragment) error {
+			return errors.New("failed")
+		},
+	}, nil)
+	_, err := pr.Parse(newSource("a"))
+	require.Error(t, err)
+	require.Equal(t, llp.CodeAction, llp.ToDiagnostic(err).Code)
+}
@@ -0,0 +1,435 @@
+package parser
+
+import (
+	"errors"
+	"sort"
+)
+
+// NewToken creates a synthetic token of the given text
+// for insertion into a parse-tree (see Editor)
+func NewToken(kind FragmentKind, text string) *Token {
+	file := &SourceFile{Src: []rune(text)}
+	return &Token{
+		VKind:  kind,
+		VBegin: file.cursor(0),
+		VEnd:   file.cursor(uint(len(file.Src))),
+	}
+}
+
+// NewConstruct creates a synthetic construct of the given elements
+// for insertion into a parse-tree (see Editor).
+// The source code of the construct is the concatenation
+// of the source code of its elements
+func NewConstruct(kind FragmentKind, elements ...Fragment) *Construct {
+	var src []rune
+	for _, el := range elements {
+		src = append(src, el.Src()...)
+	}
+	return &Construct{
+		Token:     NewToken(kind, string(src)),
+		VElements: elements,
+	}
+}
+
+// editedElement represents an element of an edited fragment
+type editedElement struct {
+	frag  Fragment
+	label string
+
+	// at is the index of an inserted fragment in the regenerated source
+	// and is nil for fragments of the original tree
+	at *uint
+
+	// after is the anchor the fragment was inserted after, if any
+	after Fragment
+}
+
+// editClass defines the order of edits at the same position
+type editClass int
+
+const (
+	editInsertAfter editClass = iota
+	editInsertBefore
+	editReplace
+)
+
+// textEdit represents the replacement of a range of the original source
+type textEdit struct {
+	begin    uint
+	end      uint
+	class    editClass
+	depth    int
+	inserted []editedElement
+}
+
+// before returns true if the edit must be applied before the other one
+func (te *textEdit) before(other *textEdit) bool {
+	switch {
+	case te.begin != other.begin:
+		return te.begin < other.begin
+	case te.class != other.class:
+		return te.class < other.class
+	case te.class == editInsertAfter:
+		// Insertions after inner fragments stay inside outer fragments
+		return te.depth > other.depth
+	}
+	// Insertions before outer fragments stay outside inner fragments
+	return te.depth < other.depth
+}
+
+// Editor records modifications of a parse-tree and regenerates
+// the source code and the parse-tree from them.
+// Untouched source code including hidden fragments is preserved as is.
+// The original parse-tree is never modified
+type Editor struct {
+	tree     *Tree
+	elements map[Fragment][]editedElement
+	removed  map[Fragment]bool
+	edits    []textEdit
+}
+
+// NewEditor creates a new editor for the tree of the given root fragment
+func NewEditor(root Fragment) *Editor {
+	return &Editor{
+		tree:     NewTree(root),
+		elements: map[Fragment][]editedElement{},
+		removed:  map[Fragment]bool{},
+	}
+}
+
+// parent returns the parent of the fragment to be edited
+func (ed *Editor) parent(fragment Fragment) (Fragment, error) {
+	if !ed.tree.Contains(fragment) {
+		return nil, errors.New("fragment isn't part of the edited tree")
+	}
+	parent := ed.tree.Parent(fragment)
+	if parent == nil {
+		return nil, errors.New("the root fragment can't be edited")
+	}
+	for frag := fragment; frag != nil; frag = ed.tree.Parent(frag) {
+		if ed.removed[frag] {
+			return nil, errors.New("fragment was removed")
+		}
+	}
+	return parent, nil
+}
+
+// remove marks the fragment as removed. Fragments containing edited
+// fragments can't be removed because the edits would be lost
+func (ed *Editor) remove(fragment Fragment) error {
+	for edited := range ed.elements {
+		if edited == fragment {
+			return errors.New("fragment contains edited fragments")
+		}
+		for _, anc := range ed.tree.Ancestors(edited) {
+			if anc == fragment {
+				return errors.New("fragment contains edited fragments")
+			}
+		}
+	}
+	ed.removed[fragment] = true
+	return nil
+}
+
+// edit returns the edited elements of the parent of the fragment
+// and the index of the fragment among them
+func (ed *Editor) edit(fragment Fragment) (
+	parent Fragment,
+	elements []editedElement,
+	index int,
+	err error,
+) {
+	if parent, err = ed.parent(fragment); err != nil {
+		return nil, nil, 0, err
+	}
+	elements, ok := ed.elements[parent]
+	if !ok {
+		ct, _ := parent.(*Construct)
+		for ix, el := range parent.Elements() {
+			label := ""
+			if ct != nil {
+				label = ct.Label(ix)
+			}
+			elements = append(elements, editedElement{frag: el, label: label})
+		}
+	}
+	for ix, el := range elements {
+		if el.frag == fragment && el.at == nil {
+			return parent, elements, ix, nil
+		}
+	}
+	panic("edited fragment missing in its parent")
+}
+
+// Replace replaces the fragment by the given one
+// which keeps the label of the replaced fragment
+func (ed *Editor) Replace(fragment, with Fragment) error {
+	if with == nil {
+		return errors.New("missing replacement fragment")
+	}
+	parent, elements, ix, err := ed.edit(fragment)
+	if err != nil {
+		return err
+	}
+	if err := ed.remove(fragment); err != nil {
+		return err
+	}
+	elements[ix] = editedElement{
+		frag:  with,
+		label: elements[ix].label,
+		at:    new(uint),
+	}
+	ed.elements[parent] = elements
+	ed.edits = append(ed.edits, textEdit{
+		begin:    fragment.Begin().Index,
+		end:      fragment.End().Index,
+		class:    editReplace,
+		inserted: []editedElement{elements[ix]},
+	})
+	return nil
+}
+
+// Delete removes the fragment from its parent
+func (ed *Editor) Delete(fragment Fragment) error {
+	parent, elements, ix, err := ed.edit(fragment)
+	if err != nil {
+		return err
+	}
+	if err := ed.remove(fragment); err != nil {
+		return err
+	}
+	ed.elements[parent] = append(elements[:ix], elements[ix+1:]...)
+	ed.edits = append(ed.edits, textEdit{
+		begin: fragment.Begin().Index,
+		end:   fragment.End().Index,
+		class: editReplace,
+	})
+	return nil
+}
+
+// InsertBefore inserts the given fragments before the anchor fragment
+func (ed *Editor) InsertBefore(anchor Fragment, fragments ...Fragment) error {
+	return ed.insert(anchor, editInsertBefore, fragments)
+}
+
+// InsertAfter inserts the given fragments after the anchor fragment
+func (ed *Editor) InsertAfter(anchor Fragment, fragments ...Fragment) error {
+	return ed.insert(anchor, editInsertAfter, fragments)
+}
+
+func (ed *Editor) insert(
+	anchor Fragment,
+	class editClass,
+	fragments []Fragment,
+) error {
+	parent, elements, ix, err := ed.edit(anchor)
+	if err != nil {
+		return err
+	}
+	at := anchor.Begin().Index
+	var after Fragment
+	if class == editInsertAfter {
+		at, after = anchor.End().Index, anchor
+		// Keep the fragments inserted after the anchor before
+		// since their source code precedes the inserted one
+		ix++
+		for ix < len(elements) && elements[ix].after == anchor {
+			ix++
+		}
+	}
+
+	inserted := make([]editedElement, len(fragments))
+	for i, frag := range fragments {
+		if frag == nil {
+			return errors.New("missing inserted fragment")
+		}
+		inserted[i] = editedElement{frag: frag, at: new(uint), after: after}
+	}
+
+	edited := make([]editedElement, 0, len(elements)+len(inserted))
+	edited = append(edited, elements[:ix]...)
+	edited = append(edited, inserted...)
+	ed.elements[parent] = append(edited, elements[ix:]...)
+	ed.edits = append(ed.edits, textEdit{
+		begin:    at,
+		end:      at,
+		class:    class,
+		depth:    ed.tree.Depth(anchor),
+		inserted: inserted,
+	})
+	return nil
+}
+
+// Apply regenerates the source file and the parse-tree from the recorded
+// edits. Cursors of the regenerated parse-tree point into the regenerated
+// source file, which keeps the name of the original one
+func (ed *Editor) Apply() (*SourceFile, Fragment) {
+	root := ed.tree.Root()
+	original := root.Begin().File
+
+	edits := make([]textEdit, len(ed.edits))
+	copy(edits, ed.edits)
+	sort.SliceStable(edits, func(i, j int) bool {
+		return edits[i].before(&edits[j])
+	})
+
+	var src []rune
+	pos := uint(0)
+	for _, edit := range edits {
+		src = append(src, original.Src[pos:edit.begin]...)
+		for _, el := range edit.inserted {
+			*el.at = uint(len(src))
+			src = append(src, el.frag.Src()...)
+		}
+		pos = edit.end
+	}
+	src = append(src, original.Src[pos:]...)
+
+	rg := &regeneration{
+		editor: ed,
+		edits:  edits,
+		file: &SourceFile{
+			Name:        original.Name,
+			Src:         src,
+			TabWidth:    original.TabWidth,
+			LineEndings: original.LineEndings,
+		},
+	}
+	return rg.file, rg.rebuild(root)
+}
+
+// regeneration represents the rebuilding of an edited parse-tree
+type regeneration struct {
+	editor *Editor
+	edits  []textEdit
+	file   *SourceFile
+}
+
+// low maps an index of the original source to the regenerated source
+// placing it before any insertions at the same index
+func (rg *regeneration) low(index uint) uint {
+	mapped := int(index)
+	for _, edit := range rg.edits {
+		if edit.end > index || edit.end == index && edit.begin == index {
+			continue
+		}
+		mapped -= int(edit.end - edit.begin)
+		for _, el := range edit.inserted {
+			mapped += len(el.frag.Src())
+		}
+	}
+	return uint(mapped)
+}
+
+// high maps an index of the original source to the regenerated source
+// placing it after any insertions at the same index
+func (rg *regeneration) high(index uint) uint {
+	mapped := rg.low(index)
+	for _, edit := range rg.edits {
+		if edit.begin == index && edit.end == index {
+			for _, el := range edit.inserted {
+				mapped += uint(len(el.frag.Src()))
+			}
+		}
+	}
+	return mapped
+}
+
+func (rg *regeneration) fragment(
+	kind FragmentKind,
+	begin uint,
+	end uint,
+	construct bool,
+	elements []Fragment,
+	labels []string,
+) Fragment {
+	tk := &Token{
+		VKind:  kind,
+		VBegin: rg.file.cursor(begin),
+		VEnd:   rg.file.cursor(end),
+	}
+	if !construct {
+		return tk
+	}
+	return &Construct{Token: tk, VElements: elements, VLabels: labels}
+}
+
+// rebuild rebuilds a fragment of the original tree
+func (rg *regeneration) rebuild(frag Fragment) Fragment {
+	begin := rg.high(frag.Begin().Index)
+	end := rg.low(frag.End().Index)
+	if isToken(frag) {
+		return rg.fragment(frag.Kind(), begin, end, false, nil, nil)
+	}
+
+	elements, edited := rg.editor.elements[frag]
+	if !edited {
+		ct, _ := frag.(*Construct)
+		for ix, el := range frag.Elements() {
+			label := ""
+			if ct != nil {
+				label = ct.Label(ix)
+			}
+			elements = append(elements, editedElement{frag: el, label: label})
+		}
+	}
+
+	var children []Fragment
+	var labels []string
+	for ix, el := range elements {
+		var child Fragment
+		if el.at != nil {
+			child = rg.place(el.frag, *el.at)
+		} else {
+			child = rg.rebuild(el.frag)
+		}
+		children = append(children, child)
+		if el.label != "" {
+			if labels == nil {
+				labels = make([]string, len(elements))
+			}
+			labels[ix] = el.label
+		}
+	}
+
+	if len(children) > 0 {
+		if first := children[0].Begin().Index; first < begin {
+			begin = first
+		}
+		if last := children[len(children)-1].End().Index; last > end {
+			end = last
+		}
+	}
+	if end < begin {
+		end = begin
+	}
+	return rg.fragment(frag.Kind(), begin, end, true, children, labels)
+}
+
+// place rebuilds an inserted fragment at the given index
+func (rg *regeneration) place(frag Fragment, at uint) Fragment {
+	end := at + uint(len(frag.Src()))
+	if isToken(frag) {
+		return rg.fragment(frag.Kind(), at, end, false, nil, nil)
+	}
+
+	var labels []string
+	if ct, ok := frag.(*Construct); ok && ct.VLabels != nil {
+		labels = append([]string(nil), ct.VLabels...)
+	}
+
+	// Elements sharing the source file of the fragment keep their offset,
+	// synthetic elements are concatenated
+	elements := frag.Elements()
+	children := make([]Fragment, len(elements))
+	next := at
+	for ix, el := range elements {
+		elAt := next
+		if el.Begin().File == frag.Begin().File {
+			elAt = at + el.Begin().Index - frag.Begin().Index
+		}
+		children[ix] = rg.place(el, elAt)
+		next = children[ix].End().Index
+	}
+	return rg.fragment(frag.Kind(), at, end, true, children, labels)
+}
@@ -0,0 +1,184 @@
+package parser_test
+
+import (
+	"testing"
+
+	llp "github.com/romshark/llparser"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEditorReplace(t *testing.T) {
+	pr := newParser(t, newFunctionGrammar(), nil)
+	mainFrag := mustParse(t, pr, newSource("fn f{a b}\nfn g{\n  a \\{a}\n}"))
+	ed := llp.NewEditor(mainFrag)
+	for _, match := range queryMatches(t, `word[text="a"]`, mainFrag) {
+		require.NoError(t, ed.Replace(
+			match.Fragment,
+			llp.NewToken(FrWord, "alpha"),
+		))
+	}
+	src, edited := ed.Apply()
+
+	expected := "fn f{alpha b}\nfn g{\n  alpha \\{alpha}\n}"
+	require.Equal(t, "test.txt", src.Name)
+	require.Equal(t, expected, string(src.Src))
+	requireSameTree(t, mustParse(t, pr, newSource(expected)), edited)
+	CheckCursor(t, src, edited.Begin(), 1, 1)
+	CheckCursor(t, src, edited.End(), 4, 2)
+
+	// The original tree is unchanged
+	require.Equal(t, "fn f{a b}\nfn g{\n  a \\{a}\n}", string(mainFrag.Src()))
+}
+
+func TestEditorDelete(t *testing.T) {
+	pr := newParser(t, newFunctionGrammar(), nil)
+	mainFrag := mustParse(t, pr, newSource("fn f{a b c}\nfn g{d}\n"))
+	funcs := queryMatches(t, "function", mainFrag)
+	ids := queryMatches(t, "body > word", mainFrag)
+
+	ed := llp.NewEditor(mainFrag)
+	require.NoError(t, ed.Delete(funcs[1].Fragment))
+	require.NoError(t, ed.Delete(ids[1].Fragment))
+	src, edited := ed.Apply()
+
+	expected := "fn f{a  c}\n\n"
+	require.Equal(t, expected, string(src.Src))
+	requireSameTree(t, mustParse(t, pr, newSource(expected)), edited)
+}
+
+func TestEditorInsert(t *testing.T) {
+	pr := newParser(t, newFunctionGrammar(), nil)
+	mainFrag := mustParse(t, pr, newSource("fn f{a b}"))
+	fn := queryMatches(t, "function", mainFrag)[0].Fragment
+	ids := queryMatches(t, "body > word", mainFrag)
+
+	ed := llp.NewEditor(mainFrag)
+	require.NoError(t, ed.InsertBefore(
+		ids[1].Fragment,
+		llp.NewToken(FrWord, "x"),
+		llp.NewToken(FrSpace, " "),
+	))
+	require.NoError(t, ed.InsertAfter(
+		ids[1].Fragment,
+		llp.NewToken(FrSpace, " "),
+		llp.NewToken(FrWord, "y"),
+	))
+	require.NoError(t, ed.InsertAfter(
+		fn,
+		llp.NewToken(FrSpace, "\n"),
+		llp.NewConstruct(
+			kindFunction,
+			llp.NewToken(FrWord, "fn"),
+			llp.NewToken(FrSpace, " "),
+			llp.NewToken(FrWord, "g"),
+			llp.NewToken(FrWord, "{"),
+			llp.NewConstruct(kindBody, llp.NewToken(FrWord, "z")),
+			llp.NewToken(FrWord, "}"),
+		),
+	))
+	src, edited := ed.Apply()
+	require.Equal(t, "fn f{a x b y}\nfn g{z}", string(src.Src))
+
+	elems := edited.Elements()
+	require.Len(t, elems, 3)
+	checkFrag(t, src, elems[0], kindFunction, C{1, 1}, C{1, 14}, 5)
+	checkFrag(t, src, elems[1], FrSpace, C{1, 14}, C{2, 1}, 0)
+	checkFrag(t, src, elems[2], kindFunction, C{2, 1}, C{2, 8}, 6)
+	require.Equal(t, "fn g{z}", string(elems[2].Src()))
+
+	body := elems[0].Elements()[3]
+	checkFrag(t, src, body, kindBody, C{1, 6}, C{1, 13}, 6)
+	for ix, expected := range []string{"a", "x", " ", "b", " ", "y"} {
+		require.Equal(t, expected, string(body.Elements()[ix].Src()))
+	}
+	checkFrag(t, src, body.Elements()[3], FrWord, C{1, 10}, C{1, 11}, 0)
+
+	inner := elems[2].Elements()[4]
+	checkFrag(t, src, inner, kindBody, C{2, 6}, C{2, 7}, 1)
+	checkFrag(t, src, inner.Elements()[0], FrWord, C{2, 6}, C{2, 7}, 0)
+}
+
+func TestEditorMove(t *testing.T) {
+	pr := newParser(t, newFunctionGrammar(), nil)
+	mainFrag := mustParse(t, pr, newSource("fn f{a \\{b}} fn g{c}"))
+	lambda := queryMatches(t, "lambda", mainFrag)[0].Fragment
+	c := queryMatches(t, `word[text="c"]`, mainFrag)[0].Fragment
+
+	// Original fragments keep their hidden source code when inserted
+	ed := llp.NewEditor(mainFrag)
+	require.NoError(t, ed.Replace(c, lambda))
+	src, edited := ed.Apply()
+
+	expected := "fn f{a \\{b}} fn g{\\{b}}"
+	require.Equal(t, expected, string(src.Src))
+	requireSameTree(t, mustParse(t, pr, newSource(expected)), edited)
+}
+
+func TestEditorErr(t *testing.T) {
+	pr := newParser(t, newFunctionGrammar(), nil)
+	mainFrag := mustParse(t, pr, newSource("fn f{a \\{b}}"))
+	fn := queryMatches(t, "function", mainFrag)[0].Fragment
+	lambda := queryMatches(t, "lambda", mainFrag)[0].Fragment
+	b := queryMatches(t, `word[text="b"]`, mainFrag)[0].Fragment
+
+	ed := llp.NewEditor(mainFrag)
+	require.EqualError(
+		t,
+		ed.Delete(mainFrag),
+		"the root fragment can't be edited",
+	)
+	require.EqualError(
+		t,
+		ed.Delete(llp.NewToken(FrWord, "x")),
+		"fragment isn't part of the edited tree",
+	)
+	require.EqualError(
+		t,
+		ed.Replace(b, nil),
+		"missing replacement fragment",
+	)
+	require.EqualError(
+		t,
+		ed.InsertAfter(b, nil),
+		"missing inserted fragment",
+	)
+
+	require.NoError(t, ed.Replace(b, llp.NewToken(FrWord, "x")))
+	require.EqualError(
+		t,
+		ed.Delete(fn),
+		"fragment contains edited fragments",
+	)
+	require.EqualError(
+		t,
+		ed.Replace(lambda, llp.NewToken(FrWord, "x")),
+		"fragment contains edited fragments",
+	)
+	require.EqualError(t, ed.Delete(b), "fragment was removed")
+
+	src, _ := ed.Apply()
+	require.Equal(t, "fn f{a \\{x}}", string(src.Src))
+}
+
+func TestEditorInsertRepeatedly(t *testing.T) {
+	pr := newParser(t, newListGrammar(), nil)
+	mainFrag := mustParse(t, pr, newSource("a,b"))
+	a, b := mainFrag.Elements()[0], mainFrag.Elements()[2]
+
+	// Fragments inserted at the same anchor keep the order of insertion
+	ed := llp.NewEditor(mainFrag)
+	for _, text := range []string{"w", "x"} {
+		require.NoError(t, ed.InsertAfter(a, llp.NewToken(FrWord, text)))
+		require.NoError(t, ed.InsertBefore(b, llp.NewToken(FrWord, text)))
+	}
+	for _, text := range []string{"y", "z"} {
+		require.NoError(t, ed.InsertBefore(a, llp.NewToken(FrWord, text)))
+		require.NoError(t, ed.InsertAfter(b, llp.NewToken(FrWord, text)))
+	}
+	src, edited := ed.Apply()
+	require.Equal(t, "yzawx,wxbyz", string(src.Src))
+	require.NoError(t, llp.VerifyLossless(edited))
+	require.Equal(t, []string{
+		"y", "z", "a", "w", "x", ",", "w", "x", "b", "y", "z",
+	}, fragSrc(edited.Elements()))
+}
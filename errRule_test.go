@@ -0,0 +1,122 @@
+package parser_test
+
+import (
+	"fmt"
+	"testing"
+
+	llp "github.com/romshark/llparser"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRuleErrRule(t *testing.T) {
+	t.Run("PropagatedFailure", func(t *testing.T) {
+		item := newItemRule()
+		pr := newParser(t, &llp.Rule{
+			Designation: "list",
+			Pattern: llp.Sequence{
+				&llp.Exact{Expectation: []rune("[")},
+				item,
+				&llp.Repeated{
+					Pattern: llp.Sequence{termSeparator, item},
+				},
+				&llp.Exact{Expectation: []rune("]")},
+			},
+		}, nil)
+
+		mainFrag, err := pr.Parse(newSource("[baz]"))
+		require.Error(t, err)
+		require.Equal(t, `unknown item "baz" at test.txt:1:2`, err.Error())
+		require.Nil(t, mainFrag)
+	})
+
+	t.Run("SwallowedFailure", func(t *testing.T) {
+		// The failure of the item rule is swallowed by the repetition
+		// but the parse fails at the position of the failed rule
+		item := newItemRule()
+		pr := newParser(t, &llp.Rule{
+			Designation: "list",
+			Pattern: &llp.Repeated{
+				Pattern: llp.Sequence{
+					item,
+					&llp.Repeated{Max: 1, Pattern: termSeparator},
+				},
+			},
+		}, nil)
+
+		mainFrag, err := pr.Parse(newSource("foo,bar,baz"))
+		require.Error(t, err)
+		require.Equal(t, `unknown item "baz" at test.txt:1:9`, err.Error())
+		require.Nil(t, mainFrag)
+	})
+
+	t.Run("PrecedesErrGrammar", func(t *testing.T) {
+		item := newItemRule()
+		errGrammar := &llp.Rule{
+			Pattern: termLatinWord,
+			Action: func(*llp.Context, llp.Fragment) error {
+				return fmt.Errorf("error grammar")
+			},
+		}
+		pr := newParser(t, &llp.Rule{
+			Designation: "list",
+			Pattern:     llp.Sequence{item, termSeparator, item},
+		}, errGrammar)
+
+		mainFrag, err := pr.Parse(newSource("foo,baz"))
+		require.Error(t, err)
+		require.Equal(t, `unknown item "baz" at test.txt:1:5`, err.Error())
+		require.Nil(t, mainFrag)
+	})
+
+	t.Run("InnermostRule", func(t *testing.T) {
+		item := newItemRule()
+		pr := newParser(t, &llp.Rule{
+			Designation: "list",
+			Pattern:     llp.Sequence{item, termSeparator, item},
+			ErrRule: &llp.Rule{
+				Pattern: &llp.Repeated{Pattern: termLatinWord},
+				Action: func(*llp.Context, llp.Fragment) error {
+					return fmt.Errorf("invalid list")
+				},
+			},
+		}, nil)
+
+		mainFrag, err := pr.Parse(newSource("foo,baz"))
+		require.Error(t, err)
+		require.Equal(t, `unknown item "baz" at test.txt:1:5`, err.Error())
+		require.Nil(t, mainFrag)
+	})
+
+	t.Run("Stack", func(t *testing.T) {
+		var designations []string
+		item := newItemRule()
+		item.ErrRule.Action = func(ctx *llp.Context, _ llp.Fragment) error {
+			for _, frame := range ctx.Stack() {
+				designations = append(designations, frame.Rule.Designation)
+			}
+			return fmt.Errorf("unknown item")
+		}
+		pr := newParser(t, &llp.Rule{
+			Designation: "list",
+			Pattern:     llp.Sequence{item, termSeparator, item},
+		}, nil)
+
+		_, err := pr.Parse(newSource("foo,baz"))
+		require.Error(t, err)
+		require.Equal(t, []string{"list", "item", ""}, designations)
+	})
+
+	t.Run("NoMatch", func(t *testing.T) {
+		item := newItemRule()
+		pr := newParser(t, &llp.Rule{
+			Designation: "list",
+			Pattern:     llp.Sequence{item, termSeparator, item},
+		}, nil)
+
+		mainFrag, err := pr.Parse(newSource("foo,;"))
+		require.Error(t, err)
+		require.Equal(t, "unexpected token, expected {item} at test.txt:1:5",
+			err.Error())
+		require.Nil(t, mainFrag)
+	})
+}
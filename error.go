@@ -1,35 +1,126 @@
 package parser
 
-import "fmt"
+import (
+	"fmt"
+	"strings"
+)
 
 // Err represents a generic parser error
 type Err struct {
 	Err error
 	At  Cursor
+
+	// Code optionally identifies the kind of error (see ToDiagnostic)
+	Code string
+
+	// Stack holds the rules active when the error occurred
+	// where the last frame is the innermost rule
+	Stack []StackFrame
+
+	// verbose includes the stack in the message (see Parser.ErrorStacks)
+	verbose bool
+
+	// kinds defines the names of undesignated rules of the stack
+	kinds *KindRegistry
 }
 
 func (err *Err) Error() string {
-	return fmt.Sprintf("%s at %s", err.Err, err.At.String())
+	msg := fmt.Sprintf("%s at %s", err.Err, err.At.String())
+	if err.verbose {
+		msg += formatStack(err.Stack, err.kinds)
+	}
+	return msg
 }
 
 // ErrUnexpectedToken represents a parser error
 type ErrUnexpectedToken struct {
 	At       Cursor
 	Expected Pattern
+
+	// committed is true when the error occurred after a cut
+	committed bool
+
+	// Suggestions holds the expected exact terminals similar to the
+	// unexpected source code ordered by similarity
+	Suggestions []Suggestion
+
+	// kinds defines the names of undesignated expected kinds
+	kinds *KindRegistry
+
+	// expectedAt is the pattern expected at the position of the error
+	// unlike Expected which may have begun before it.
+	// Expected is expected there if expectedAt is nil
+	expectedAt Pattern
+
+	// ruleErr is the error of the error-rule of the innermost
+	// failed rule that caused this error
+	ruleErr error
+
+	// Stack holds the rules active when the error occurred
+	// where the last frame is the innermost rule.
+	// It's only recorded for errors returned by the parser
+	Stack []StackFrame
+
+	// verbose includes the stack in the message (see Parser.ErrorStacks)
+	verbose bool
 }
 
 func (err *ErrUnexpectedToken) Error() string {
+	var stack string
+	if err.verbose {
+		stack = formatStack(err.Stack, err.kinds)
+	}
 	if err.Expected == nil {
 		return fmt.Sprintf(
 			"unexpected token at %s",
 			err.At,
-		)
+		) + stack
 	}
-	return fmt.Sprintf(
+	msg := fmt.Sprintf(
 		"unexpected token, expected {%s} at %s",
-		err.Expected.Desig(),
+		designation(err.Expected, err.kinds),
 		err.At,
 	)
+	if len(err.Suggestions) > 0 {
+		msg += ", did you mean " + err.suggested() + "?"
+	}
+	return msg + stack
+}
+
+// suggested returns the quoted suggestions
+func (err *ErrUnexpectedToken) suggested() string {
+	quoted := make([]string, len(err.Suggestions))
+	for ix, sg := range err.Suggestions {
+		quoted[ix] = "'" + sg.Text + "'"
+	}
+	return strings.Join(quoted, " or ")
+}
+
+// formatStack formats the rule stack from the outermost to the innermost rule
+func formatStack(stack []StackFrame, kinds *KindRegistry) string {
+	if len(stack) < 1 {
+		return ""
+	}
+	frames := make([]string, len(stack))
+	for ix, frame := range stack {
+		name := designation(frame.Rule, kinds)
+		if name == "" {
+			name = fmt.Sprintf("%p", frame.Rule)
+		}
+		frames[ix] = name + " at " + frame.Begin.String()
+	}
+	return " (rule stack: " + strings.Join(frames, " > ") + ")"
+}
+
+// expect sets the expected pattern that began at the given position
+// keeping track of the pattern expected at the position of the error
+func (err *ErrUnexpectedToken) expect(pattern Pattern, begin Cursor) {
+	if err.At.Index == begin.Index {
+		err.expectedAt = pattern
+	} else if err.expectedAt == nil {
+		err.expectedAt = err.Expected
+	}
+	err.Expected = pattern
 }
 
 type errEOF struct{}
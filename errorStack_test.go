@@ -0,0 +1,111 @@
+package parser_test
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	llp "github.com/romshark/llparser"
+	"github.com/stretchr/testify/require"
+)
+
+// stackDesignations returns the designations of the rules of the stack
+func stackDesignations(stack []llp.StackFrame) []string {
+	designations := make([]string, len(stack))
+	for ix, frame := range stack {
+		designations[ix] = frame.Rule.Designation
+	}
+	return designations
+}
+
+func TestErrorStack(t *testing.T) {
+	newListGrammar := func() *llp.Rule {
+		item := &llp.Rule{
+			Designation: "item",
+			Pattern:     llp.Either{testR_foo, testR_bar},
+		}
+		return &llp.Rule{
+			Designation: "list",
+			Pattern:     llp.Sequence{item, termSeparator, item},
+		}
+	}
+
+	t.Run("UnexpectedToken", func(t *testing.T) {
+		pr := newParser(t, newListGrammar(), nil)
+
+		src := newSource("foo,baz")
+		_, err := pr.Parse(src)
+		var unexpErr *llp.ErrUnexpectedToken
+		require.True(t, errors.As(err, &unexpErr))
+		// The failure occurred in the last option of the item
+		require.Equal(t, []string{"list", "item", "keyword bar"},
+			stackDesignations(unexpErr.Stack))
+		CheckCursor(t, src, unexpErr.Stack[0].Begin, 1, 1)
+		CheckCursor(t, src, unexpErr.Stack[1].Begin, 1, 5)
+		CheckCursor(t, src, unexpErr.Stack[2].Begin, 1, 5)
+		require.Equal(t, "unexpected token, expected {item} "+
+			"at test.txt:1:5, did you mean 'bar'?", err.Error())
+	})
+
+	t.Run("UnexpectedTokenMessage", func(t *testing.T) {
+		pr := newParser(t, newListGrammar(), nil)
+		pr.ErrorStacks = true
+
+		_, err := pr.Parse(newSource("foo,baz"))
+		require.Error(t, err)
+		require.Equal(t, "unexpected token, expected {item} "+
+			"at test.txt:1:5, did you mean 'bar'? (rule stack: "+
+			"list at test.txt:1:1 > item at test.txt:1:5 > "+
+			"keyword bar at test.txt:1:5)",
+			err.Error())
+	})
+
+	t.Run("RecursionLimit", func(t *testing.T) {
+		rb := &llp.Rule{Designation: "B"}
+		ra := &llp.Rule{Designation: "A", Pattern: rb}
+		rb.Pattern = ra
+
+		pr := newParser(t, ra, nil)
+		pr.MaxRecursionLevel = 2
+		pr.ErrorStacks = true
+
+		_, err := pr.Parse(newSource("a"))
+		var parseErr *llp.Err
+		require.True(t, errors.As(err, &parseErr))
+		require.Equal(t, llp.CodeRecursionLimit, parseErr.Code)
+		// The stack ends with the rule exceeding the limit
+		require.Equal(t, []string{"A", "B", "A", "B", "A"},
+			stackDesignations(parseErr.Stack))
+		require.Equal(t, fmt.Sprintf(
+			"max recursion level exceeded at rule %p (\"A\") "+
+				"at test.txt:1:1 (rule stack: A at test.txt:1:1 > "+
+				"B at test.txt:1:1 > A at test.txt:1:1 > "+
+				"B at test.txt:1:1 > A at test.txt:1:1)",
+			ra,
+		), err.Error())
+	})
+
+	t.Run("Action", func(t *testing.T) {
+		inner := &llp.Rule{
+			Designation: "inner",
+			Pattern:     testR_foo,
+			Action: func(*llp.Context, llp.Fragment) error {
+				return fmt.Errorf("rejected")
+			},
+		}
+		pr := newParser(t, &llp.Rule{
+			Designation: "outer",
+			Pattern:     llp.Sequence{testR_bar, inner},
+		}, nil)
+		pr.DeferActions = true
+
+		src := newSource("barfoo")
+		_, err := pr.Parse(src)
+		var parseErr *llp.Err
+		require.True(t, errors.As(err, &parseErr))
+		require.Equal(t, []string{"outer", "inner"},
+			stackDesignations(parseErr.Stack))
+		CheckCursor(t, src, parseErr.Stack[1].Begin, 1, 4)
+		require.Equal(t, "rejected at test.txt:1:4", err.Error())
+	})
+}
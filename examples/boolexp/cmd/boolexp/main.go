@@ -53,7 +53,8 @@ func main() {
 				Indentation: []byte(" "),
 				Prefix:      []byte(" "),
 				Format: func(frag llp.Fragment) (head, body []byte) {
-					head = []byte(parser.FragKindString(frag.Kind()))
+					name, _ := parser.Kinds.Name(frag.Kind())
+					head = []byte(name)
 					return
 				},
 			},
@@ -2,6 +2,8 @@ package parser
 
 import llp "github.com/romshark/llparser"
 
+//go:generate go run github.com/romshark/llparser/cmd/llpkinds -type FragKind -trimprefix Fr
+
 // FragKind represents a dick-lang fragment kind
 type FragKind = llp.FragmentKind
 
@@ -24,21 +26,26 @@ const (
 	FrExprTerm
 
 	// FrConstTrue represents the constant boolean value "true"
+	//llp:category constant
 	FrConstTrue
 
 	// FrConstFalse represents the constant boolean value "false"
+	//llp:category constant
 	FrConstFalse
 
 	// FrVarRef represents a variable reference
 	FrVarRef
 
 	// FrOprOr represents the logical or-operator
+	//llp:category operator
 	FrOprOr
 
 	// FrOprAnd represents the logical and-operator
+	//llp:category operator
 	FrOprAnd
 
 	// FrOprNeg represents the boolean negation operator
+	//llp:category operator
 	FrOprNeg
 
 	// FrParOpen represents the opening parenthesis
@@ -47,36 +54,3 @@ const (
 	// FrParClose represents the closing parenthesis
 	FrParClose
 )
-
-// FragKindString translates the kind identifier to its name
-func FragKindString(kind llp.FragmentKind) string {
-	switch kind {
-	case FrSpace:
-		return "Space"
-	case FrExpr:
-		return "Expr"
-	case FrExprParentheses:
-		return "ExprParentheses"
-	case FrExprFactor:
-		return "ExprFactor"
-	case FrExprTerm:
-		return "ExprTerm"
-	case FrConstTrue:
-		return "ConstTrue"
-	case FrConstFalse:
-		return "ConstFalse"
-	case FrVarRef:
-		return "VarRef"
-	case FrOprOr:
-		return "OprOr"
-	case FrOprAnd:
-		return "OprAnd"
-	case FrOprNeg:
-		return "OprNeg"
-	case FrParOpen:
-		return "ParOpen"
-	case FrParClose:
-		return "ParClose"
-	}
-	return ""
-}
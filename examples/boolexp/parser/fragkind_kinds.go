@@ -0,0 +1,29 @@
+// Code generated by "llpkinds -type FragKind -trimprefix Fr"; DO NOT EDIT.
+
+package parser
+
+import llp "github.com/romshark/llparser"
+
+// Kinds holds the names of the FragKind constants
+var Kinds = func() *llp.KindRegistry {
+	kinds := &llp.KindRegistry{}
+	kinds.MustRegister(FrSpace, "Space")
+	kinds.MustRegister(FrExpr, "Expr")
+	kinds.MustRegister(FrExprParentheses, "ExprParentheses")
+	kinds.MustRegister(FrExprFactor, "ExprFactor")
+	kinds.MustRegister(FrExprTerm, "ExprTerm")
+	kinds.MustRegister(FrConstTrue, "ConstTrue")
+	kinds.Categorize(FrConstTrue, "constant")
+	kinds.MustRegister(FrConstFalse, "ConstFalse")
+	kinds.Categorize(FrConstFalse, "constant")
+	kinds.MustRegister(FrVarRef, "VarRef")
+	kinds.MustRegister(FrOprOr, "OprOr")
+	kinds.Categorize(FrOprOr, "operator")
+	kinds.MustRegister(FrOprAnd, "OprAnd")
+	kinds.Categorize(FrOprAnd, "operator")
+	kinds.MustRegister(FrOprNeg, "OprNeg")
+	kinds.Categorize(FrOprNeg, "operator")
+	kinds.MustRegister(FrParOpen, "ParOpen")
+	kinds.MustRegister(FrParClose, "ParClose")
+	return kinds
+}()
@@ -79,7 +79,7 @@ func newGrammar() *llp.Rule {
 		Pattern: llp.Sequence{
 			termParOpen,
 			optional(termSpace),
-			expression,
+			llp.Label{Name: "expression", Pattern: expression},
 			optional(termSpace),
 			termParClose,
 		},
@@ -96,7 +96,7 @@ func newGrammar() *llp.Rule {
 		},
 		llp.Sequence{
 			termOprNeg,
-			factor,
+			llp.Label{Name: "operand", Pattern: factor},
 		},
 		parentheses,
 		llp.Not{Pattern: termAnything},
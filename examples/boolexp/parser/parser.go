@@ -17,6 +17,7 @@ func NewParser() (*Parser, error) {
 	if err != nil {
 		return nil, err
 	}
+	parser.Kinds = Kinds
 	return &Parser{prs: parser}, nil
 }
 
@@ -56,22 +57,6 @@ func (pr *Parser) Parse(
 	return ast, nil
 }
 
-func findKind(
-	frags []llp.Fragment,
-	offset int,
-	kinds ...llp.FragmentKind,
-) (int, llp.Fragment) {
-	for fix := offset; fix < len(frags); fix++ {
-		frag := frags[fix]
-		for _, kind := range kinds {
-			if frag.Kind() == kind {
-				return fix, frag
-			}
-		}
-	}
-	return -1, nil
-}
-
 func parseExpr(frag llp.Fragment) ASTExpression {
 	elems := frag.Elements()
 	terms := []ASTExpression{}
@@ -84,8 +69,8 @@ func parseExpr(frag llp.Fragment) ASTExpression {
 		}
 	}
 
-	// Check for or-statements
-	if _, fr := findKind(elems, 1, FrOprOr); fr == nil {
+	// Check for or-statements consisting of multiple terms
+	if len(terms) < 2 {
 		if len(terms) < 1 {
 			return nil
 		}
@@ -109,8 +94,8 @@ func parseTerm(frag llp.Fragment) ASTExpression {
 		}
 	}
 
-	// Check for and-statements
-	if _, fr := findKind(elems, 1, FrOprAnd); fr == nil {
+	// Check for and-statements consisting of multiple factors
+	if len(factors) < 2 {
 		if len(factors) < 1 {
 			return nil
 		}
@@ -143,11 +128,11 @@ func parseFactor(frag llp.Fragment) ASTExpression {
 		// Negated factor
 		return &ASTNegated{
 			Fragment:   frag,
-			Expression: parseFactor(elems[1]),
+			Expression: parseFactor(frag.(*llp.Construct).Child("operand")),
 		}
 	case FrExprParentheses:
 		// Expression enclosed in parentheses
-		_, expr := findKind(elems[0].Elements(), 1, FrExpr)
+		expr := firstElem.(*llp.Construct).Child("expression")
 		return &ASTParentheses{
 			Fragment:   frag,
 			Expression: parseExpr(expr),
@@ -5,6 +5,7 @@ import (
 	"strings"
 	"testing"
 
+	llp "github.com/romshark/llparser"
 	"github.com/romshark/llparser/examples/boolexp/parser"
 	prs "github.com/romshark/llparser/examples/boolexp/parser"
 	"github.com/stretchr/testify/require"
@@ -51,6 +52,10 @@ func (ts Test) Exec(t *testing.T) {
 			require.NotNil(t, ast)
 			compareStringifiedAST(t, expectation.AST, ast)
 			require.Equal(t, expectation.Result, ast.Root.Val())
+
+			// The parse-tree must be lossless
+			require.NoError(t, llp.VerifyLossless(ast.Root.Fragment))
+			require.Equal(t, expr, llp.Reprint(ast.Root.Fragment))
 		})
 	}
 }
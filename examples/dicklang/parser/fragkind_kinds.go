@@ -0,0 +1,16 @@
+// Code generated by "llpkinds -type FragKind -trimprefix Fr"; DO NOT EDIT.
+
+package parser
+
+import llp "github.com/romshark/llparser"
+
+// Kinds holds the names of the FragKind constants
+var Kinds = func() *llp.KindRegistry {
+	kinds := &llp.KindRegistry{}
+	kinds.MustRegister(FrSpace, "Space")
+	kinds.MustRegister(FrBalls, "Balls")
+	kinds.MustRegister(FrShaft, "Shaft")
+	kinds.MustRegister(FrHead, "Head")
+	kinds.MustRegister(FrDick, "Dick")
+	return kinds
+}()
@@ -14,11 +14,15 @@ type ModelDick struct {
 	ShaftLength uint
 }
 
-func (mod *ModelDicks) onDickDetected(frag llp.Fragment) error {
+func onDickDetected(ctx *llp.Context, frag llp.Fragment) error {
+	mod := ctx.Value().(*ModelDicks)
+
+	shaft := frag.(*llp.Construct).Child("shaft")
+
 	// Register the newly parsed dick
 	mod.Dicks = append(mod.Dicks, ModelDick{
 		Frag:        frag,
-		ShaftLength: uint(len(frag.Elements()[1].Elements())),
+		ShaftLength: uint(len(shaft.Elements())),
 	})
 
 	return nil
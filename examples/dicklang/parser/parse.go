@@ -7,6 +7,8 @@ import (
 	llp "github.com/romshark/llparser"
 )
 
+//go:generate go run github.com/romshark/llparser/cmd/llpkinds -type FragKind -trimprefix Fr
+
 // FragKind represents a dick-lang fragment kind
 type FragKind = llp.FragmentKind
 
@@ -29,29 +31,9 @@ const (
 	FrDick
 )
 
-// FragKindString translates the kind identifier to its name
-func FragKindString(kind llp.FragmentKind) string {
-	switch kind {
-	case FrSpace:
-		return "Space"
-	case FrBalls:
-		return "Balls"
-	case FrShaft:
-		return "Shaft"
-	case FrHead:
-		return "Head"
-	case FrDick:
-		return "Dick"
-	}
-	return ""
-}
-
 // Parse parses a dick-lang file
 func Parse(fileName string, source []rune) (*ModelDicks, error) {
 
-	// Initialize model
-	mod := &ModelDicks{}
-
 	// Define the grammar
 	termHeadLeft := &llp.Exact{Kind: FrHead, Expectation: []rune("<")}
 	termHeadRight := &llp.Exact{Kind: FrHead, Expectation: []rune(">")}
@@ -88,6 +70,79 @@ func Parse(fileName string, source []rune) (*ModelDicks, error) {
 		Pattern:     &llp.Repeated{Pattern: shaftElement, Min: 2},
 	}
 
+	// Define the error rules of the dicks
+	errDickRight := &llp.Rule{
+		Pattern: llp.Either{
+			// Dick (right) without a head
+			&llp.Rule{
+				Pattern: llp.Sequence{
+					llp.Either{
+						termBalls1,
+						termBallsRight1,
+					},
+					ruleShaft,
+				},
+				Action: failWith("that dick is missing a head"),
+			},
+			// Dick (right) without balls
+			&llp.Rule{
+				Pattern: llp.Sequence{
+					ruleShaft,
+					termHeadRight,
+				},
+				Action: failWith("that dick is missing its balls"),
+			},
+			// Dick (right) too small
+			&llp.Rule{
+				Pattern: llp.Sequence{
+					llp.Either{
+						termBalls1,
+						termBallsRight1,
+					},
+					shaftElement,
+					termHeadRight,
+				},
+				Action: failWith("that dick is too small"),
+			},
+		},
+	}
+
+	errDickLeft := &llp.Rule{
+		Pattern: llp.Either{
+			// Dick (left) without a head
+			&llp.Rule{
+				Pattern: llp.Sequence{
+					ruleShaft,
+					llp.Either{
+						termBalls1,
+						termBallsLeft1,
+					},
+				},
+				Action: failWith("that dick is missing a head"),
+			},
+			// Dick (left) without balls
+			&llp.Rule{
+				Pattern: llp.Sequence{
+					termHeadLeft,
+					ruleShaft,
+				},
+				Action: failWith("that dick is missing its balls"),
+			},
+			// Dick (left) too small
+			&llp.Rule{
+				Pattern: llp.Sequence{
+					termHeadLeft,
+					shaftElement,
+					llp.Either{
+						termBalls1,
+						termBallsLeft1,
+					},
+				},
+				Action: failWith("that dick is too small"),
+			},
+		},
+	}
+
 	ruleDickRight := &llp.Rule{
 		Designation: "dick(right)",
 		Kind:        FrDick,
@@ -96,10 +151,11 @@ func Parse(fileName string, source []rune) (*ModelDicks, error) {
 				termBalls1,
 				termBallsRight1,
 			},
-			ruleShaft,
+			llp.Label{Name: "shaft", Pattern: ruleShaft},
 			termHeadRight,
 		},
-		Action: mod.onDickDetected,
+		Action:  onDickDetected,
+		ErrRule: errDickRight,
 	}
 
 	ruleDickLeft := &llp.Rule{
@@ -107,13 +163,14 @@ func Parse(fileName string, source []rune) (*ModelDicks, error) {
 		Kind:        FrDick,
 		Pattern: llp.Sequence{
 			termHeadLeft,
-			ruleShaft,
+			llp.Label{Name: "shaft", Pattern: ruleShaft},
 			llp.Either{
 				termBalls1,
 				termBallsLeft1,
 			},
 		},
-		Action: mod.onDickDetected,
+		Action:  onDickDetected,
+		ErrRule: errDickLeft,
 	}
 
 	ruleFile := &llp.Rule{
@@ -140,101 +197,38 @@ func Parse(fileName string, source []rune) (*ModelDicks, error) {
 		},
 	}
 
-	// Define error patterns
-	errRule := &llp.Rule{
-		Pattern: llp.Either{
-			// Dick (right) without a head
-			&llp.Rule{
-				Pattern: llp.Sequence{
-					llp.Either{
-						termBalls1,
-						termBallsRight1,
-					},
-					ruleShaft,
-				},
-				Action: func(llp.Fragment) error {
-					return errors.New("that dick is missing a head")
-				},
-			},
-			// Dick (left) without a head
-			&llp.Rule{
-				Pattern: llp.Sequence{
-					ruleShaft,
-					llp.Either{
-						termBalls1,
-						termBallsLeft1,
-					},
-				},
-				Action: func(llp.Fragment) error {
-					return errors.New("that dick is missing a head")
-				},
-			},
-			// Dick (right) without balls
-			&llp.Rule{
-				Pattern: llp.Sequence{
-					ruleShaft,
-					termHeadRight,
-				},
-				Action: func(llp.Fragment) error {
-					return errors.New("that dick is missing its balls")
-				},
-			},
-			// Dick (left) without balls
-			&llp.Rule{
-				Pattern: llp.Sequence{
-					termHeadLeft,
-					ruleShaft,
-				},
-				Action: func(llp.Fragment) error {
-					return errors.New("that dick is missing its balls")
-				},
-			},
-			// Dick (right) too small
-			&llp.Rule{
-				Pattern: llp.Sequence{
-					llp.Either{
-						termBalls1,
-						termBallsRight1,
-					},
-					shaftElement,
-					termHeadRight,
-				},
-				Action: func(llp.Fragment) error {
-					return errors.New("that dick is too small")
-				},
-			},
-			// Dick (left) too small
-			&llp.Rule{
-				Pattern: llp.Sequence{
-					termHeadLeft,
-					shaftElement,
-					llp.Either{
-						termBalls1,
-						termBallsLeft1,
-					},
-				},
-				Action: func(llp.Fragment) error {
-					return errors.New("that dick is too small")
-				},
-			},
-		},
-	}
-
 	// Initialize lexer and parser
-	par, err := llp.NewParser(ruleFile, errRule)
+	par, err := llp.NewParser(ruleFile, nil)
 	if err != nil {
 		return nil, fmt.Errorf("parser init: %w", err)
 	}
 
+	// Only register dicks that are part of the final parse-tree
+	par.DeferActions = true
+	par.Kinds = Kinds
+
+	// Initialize model
+	mod := &ModelDicks{}
+
 	// Parse the source file
-	mainFrag, err := par.Parse(&llp.SourceFile{
-		Name: fileName,
-		Src:  source,
-	})
+	result, err := par.ParseWith(
+		&llp.SourceFile{
+			Name: fileName,
+			Src:  source,
+		},
+		llp.ParseOptions{Value: mod},
+	)
 	if err != nil {
 		return nil, err
 	}
 
-	mod.Frag = mainFrag
+	mod.Frag = result.Fragment
 	return mod, nil
 }
+
+// failWith returns an action failing with the given error message
+func failWith(message string) llp.Action {
+	return func(*llp.Context, llp.Fragment) error {
+		return errors.New(message)
+	}
+}
@@ -3,6 +3,7 @@ package parser_test
 import (
 	"testing"
 
+	llp "github.com/romshark/llparser"
 	"github.com/romshark/llparser/examples/dicklang/parser"
 	"github.com/stretchr/testify/require"
 )
@@ -27,6 +28,14 @@ func TestParser(t *testing.T) {
 	require.Equal(t, "8xxx=xxx>", string(mod.Dicks[6].Frag.Src()))
 	require.Equal(t, "B:x:=:x>", string(mod.Dicks[7].Frag.Src()))
 	require.Equal(t, "<:=3", string(mod.Dicks[8].Frag.Src()))
+
+	for ix, expectedLen := range []uint{3, 2, 2, 4, 6, 4, 7, 6, 2} {
+		require.Equal(t, expectedLen, mod.Dicks[ix].ShaftLength)
+	}
+
+	// The parse-tree must be lossless
+	require.NoError(t, llp.VerifyLossless(mod.Frag))
+	require.Equal(t, src, llp.Reprint(mod.Frag))
 }
 
 func TestParserErr(t *testing.T) {
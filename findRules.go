@@ -24,6 +24,9 @@ func findRules(pattern Pattern, reg recursionRegister) {
 		}
 		reg[pt] = 0
 		findRules(pt.Pattern, reg)
+		if pt.ErrRule != nil {
+			findRules(pt.ErrRule, reg)
+		}
 	case Sequence:
 		for _, pt := range pt {
 			findRules(pt, reg)
@@ -34,6 +37,10 @@ func findRules(pattern Pattern, reg recursionRegister) {
 		}
 	case Not:
 		findRules(pt.Pattern, reg)
+	case Label:
+		findRules(pt.Pattern, reg)
+	case Shaped:
+		findRules(pt.Pattern, reg)
 	case *Repeated:
 		if pt == nil {
 			return
@@ -0,0 +1,310 @@
+package parser
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// JSONOptions defines the options of the JSON encoding
+// and decoding of fragment trees
+type JSONOptions struct {
+	// Kinds optionally defines the kind names. Names are encoded next to
+	// the kind numbers and take precedence over them when decoding.
+	// DefaultKinds is used when nil
+	Kinds *KindRegistry
+
+	// Src includes the source code of the root fragment and all tokens
+	Src bool
+
+	// Compact encodes cursors as arrays of offset, line and column
+	// instead of objects
+	Compact bool
+
+	// Files optionally resolves the source files referred to by name when
+	// decoding. When a file isn't resolved, it's reconstructed from the
+	// source code of the root fragment if it was included
+	Files func(name string) *SourceFile
+}
+
+// JSONEncoder encodes fragment trees as JSON writing the fragments while
+// they're walked without building an intermediate representation.
+//
+// A fragment is encoded as an object of its kind, its kind name, its
+// beginning and ending cursor, its label, its source code and its elements.
+// Source files are referred to by name. The name of the file is only
+// encoded for the root fragment and for fragments located in another file
+// than their parent. Tokens have no elements while constructs
+// always have even if they're empty:
+//
+//	{"kind":1,"name":"list","file":"a.txt",
+//	"begin":{"offset":0,"line":1,"column":1},
+//	"end":{"offset":2,"line":1,"column":3},
+//	"src":"ab","elements":[...]}
+type JSONEncoder struct {
+	w       *bufio.Writer
+	options JSONOptions
+}
+
+// NewJSONEncoder creates a new JSON encoder writing to w
+func NewJSONEncoder(w io.Writer, options JSONOptions) *JSONEncoder {
+	options.Kinds = options.Kinds.or()
+	return &JSONEncoder{w: bufio.NewWriter(w), options: options}
+}
+
+// Encode encodes the fragment tree followed by a line-break
+func (enc *JSONEncoder) Encode(fragment Fragment) error {
+	enc.fragment(fragment, "", nil, true)
+	enc.w.WriteByte('\n')
+	return enc.w.Flush()
+}
+
+func (enc *JSONEncoder) str(str string) {
+	// Encoding strings never fails
+	encoded, _ := json.Marshal(str)
+	enc.w.Write(encoded)
+}
+
+func (enc *JSONEncoder) uint(key string, val uint) {
+	enc.w.WriteString(key)
+	enc.w.WriteString(strconv.FormatUint(uint64(val), 10))
+}
+
+func (enc *JSONEncoder) cursor(cr Cursor) {
+	if enc.options.Compact {
+		enc.uint("[", cr.Index)
+		enc.uint(",", cr.Line)
+		enc.uint(",", cr.Column)
+		enc.w.WriteByte(']')
+		return
+	}
+	enc.uint(`{"offset":`, cr.Index)
+	enc.uint(`,"line":`, cr.Line)
+	enc.uint(`,"column":`, cr.Column)
+	enc.w.WriteByte('}')
+}
+
+func (enc *JSONEncoder) fragment(
+	frag Fragment,
+	label string,
+	parentFile *SourceFile,
+	root bool,
+) {
+	enc.w.WriteString(`{"kind":`)
+	enc.w.WriteString(strconv.Itoa(int(frag.Kind())))
+	if name, ok := enc.options.Kinds.Name(frag.Kind()); ok {
+		enc.w.WriteString(`,"name":`)
+		enc.str(name)
+	}
+	file := frag.Begin().File
+	if file != nil && file != parentFile {
+		enc.w.WriteString(`,"file":`)
+		enc.str(file.Name)
+	}
+	enc.w.WriteString(`,"begin":`)
+	enc.cursor(frag.Begin())
+	enc.w.WriteString(`,"end":`)
+	enc.cursor(frag.End())
+	if label != "" {
+		enc.w.WriteString(`,"label":`)
+		enc.str(label)
+	}
+
+	token := isToken(frag)
+	if enc.options.Src && (root || token) {
+		enc.w.WriteString(`,"src":`)
+		enc.str(string(frag.Src()))
+	}
+	if token {
+		enc.w.WriteByte('}')
+		return
+	}
+
+	enc.w.WriteString(`,"elements":[`)
+	ct, _ := frag.(*Construct)
+	for ix, el := range frag.Elements() {
+		if ix > 0 {
+			enc.w.WriteByte(',')
+		}
+		label := ""
+		if ct != nil {
+			label = ct.Label(ix)
+		}
+		enc.fragment(el, label, file, false)
+	}
+	enc.w.WriteString("]}")
+}
+
+// MarshalFragment encodes the fragment tree as JSON (see JSONEncoder)
+func MarshalFragment(fragment Fragment, options JSONOptions) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := NewJSONEncoder(&buf, options).Encode(fragment); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+}
+
+// jsonCursor represents an encoded cursor
+// which is either an object or an array
+type jsonCursor struct {
+	Offset uint `json:"offset"`
+	Line   uint `json:"line"`
+	Column uint `json:"column"`
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface
+func (cr *jsonCursor) UnmarshalJSON(data []byte) error {
+	if len(data) > 0 && data[0] == '[' {
+		var compact []uint
+		if err := json.Unmarshal(data, &compact); err != nil {
+			return err
+		}
+		if len(compact) != 3 {
+			return fmt.Errorf("invalid cursor: %s", data)
+		}
+		cr.Offset, cr.Line, cr.Column = compact[0], compact[1], compact[2]
+		return nil
+	}
+	type cursor jsonCursor
+	return json.Unmarshal(data, (*cursor)(cr))
+}
+
+// jsonFragment represents an encoded fragment
+type jsonFragment struct {
+	Kind     FragmentKind    `json:"kind"`
+	Name     string          `json:"name"`
+	File     *string         `json:"file"`
+	Begin    jsonCursor      `json:"begin"`
+	End      jsonCursor      `json:"end"`
+	Label    string          `json:"label"`
+	Src      *string         `json:"src"`
+	Elements *[]jsonFragment `json:"elements"`
+}
+
+// JSONDecoder decodes fragment trees encoded by a JSONEncoder
+type JSONDecoder struct {
+	dec     *json.Decoder
+	options JSONOptions
+
+	// files holds the source files resolved for the current tree
+	files map[string]*SourceFile
+}
+
+// NewJSONDecoder creates a new JSON decoder reading from r
+func NewJSONDecoder(r io.Reader, options JSONOptions) *JSONDecoder {
+	options.Kinds = options.Kinds.or()
+	return &JSONDecoder{dec: json.NewDecoder(r), options: options}
+}
+
+// Decode decodes the next fragment tree.
+// Returns io.EOF when there are no more trees
+func (dec *JSONDecoder) Decode() (Fragment, error) {
+	var root jsonFragment
+	if err := dec.dec.Decode(&root); err != nil {
+		return nil, err
+	}
+	if root.File == nil {
+		return nil, errors.New("missing source file of the root fragment")
+	}
+	dec.files = map[string]*SourceFile{}
+	return dec.fragment(&root, nil)
+}
+
+// file resolves the source file of the given name
+func (dec *JSONDecoder) file(name string, root *jsonFragment) (
+	*SourceFile,
+	error,
+) {
+	if file, ok := dec.files[name]; ok {
+		return file, nil
+	}
+	var file *SourceFile
+	if dec.options.Files != nil {
+		file = dec.options.Files(name)
+	}
+	if file == nil {
+		if root == nil || root.Src == nil || root.Begin.Offset != 0 {
+			return nil, fmt.Errorf("unknown source file %q", name)
+		}
+		// Reconstruct the file from the source code of the root fragment
+		file = &SourceFile{Name: name, Src: []rune(*root.Src)}
+	}
+	dec.files[name] = file
+	return file, nil
+}
+
+func (dec *JSONDecoder) fragment(
+	encoded *jsonFragment,
+	file *SourceFile,
+) (Fragment, error) {
+	if encoded.File != nil {
+		root := encoded
+		if file != nil {
+			root = nil
+		}
+		var err error
+		if file, err = dec.file(*encoded.File, root); err != nil {
+			return nil, err
+		}
+	}
+	if encoded.Begin.Offset > encoded.End.Offset ||
+		encoded.End.Offset > uint(len(file.Src)) {
+		return nil, fmt.Errorf(
+			"fragment span %d-%d out of range of source file %q",
+			encoded.Begin.Offset,
+			encoded.End.Offset,
+			file.Name,
+		)
+	}
+
+	kind := encoded.Kind
+	if encoded.Name != "" {
+		if named, ok := dec.options.Kinds.Kind(encoded.Name); ok {
+			kind = named
+		}
+	}
+	tk := &Token{
+		VKind: kind,
+		VBegin: Cursor{
+			Index:  encoded.Begin.Offset,
+			Line:   encoded.Begin.Line,
+			Column: encoded.Begin.Column,
+			File:   file,
+		},
+		VEnd: Cursor{
+			Index:  encoded.End.Offset,
+			Line:   encoded.End.Line,
+			Column: encoded.End.Column,
+			File:   file,
+		},
+	}
+	if encoded.Elements == nil {
+		return tk, nil
+	}
+
+	ct := &Construct{Token: tk}
+	for ix := range *encoded.Elements {
+		el := &(*encoded.Elements)[ix]
+		frag, err := dec.fragment(el, file)
+		if err != nil {
+			return nil, err
+		}
+		ct.VElements = append(ct.VElements, frag)
+		if el.Label != "" {
+			if ct.VLabels == nil {
+				ct.VLabels = make([]string, len(*encoded.Elements))
+			}
+			ct.VLabels[ix] = el.Label
+		}
+	}
+	return ct, nil
+}
+
+// UnmarshalFragment decodes a fragment tree encoded by MarshalFragment
+func UnmarshalFragment(data []byte, options JSONOptions) (Fragment, error) {
+	return NewJSONDecoder(bytes.NewReader(data), options).Decode()
+}
@@ -0,0 +1,176 @@
+package parser_test
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	llp "github.com/romshark/llparser"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMarshalFragment(t *testing.T) {
+	src := newSource("a:1")
+	mainFrag, err := newParser(t, newServerGrammar(), nil).Parse(src)
+	require.NoError(t, err)
+	kinds := newKinds(t)
+
+	t.Run("Default", func(t *testing.T) {
+		data, err := llp.MarshalFragment(mainFrag, llp.JSONOptions{})
+		require.NoError(t, err)
+		require.Equal(t, `{"kind":200,"file":"test.txt",`+
+			`"begin":{"offset":0,"line":1,"column":1},`+
+			`"end":{"offset":3,"line":1,"column":4},"elements":[`+
+			`{"kind":3,"begin":{"offset":0,"line":1,"column":1},`+
+			`"end":{"offset":1,"line":1,"column":2},"label":"name"},`+
+			`{"kind":0,"begin":{"offset":1,"line":1,"column":2},`+
+			`"end":{"offset":2,"line":1,"column":3}},`+
+			`{"kind":201,"begin":{"offset":2,"line":1,"column":3},`+
+			`"end":{"offset":3,"line":1,"column":4},"label":"port"}]}`,
+			string(data),
+		)
+	})
+
+	t.Run("Compact", func(t *testing.T) {
+		data, err := llp.MarshalFragment(mainFrag, llp.JSONOptions{
+			Kinds:   kinds,
+			Src:     true,
+			Compact: true,
+		})
+		require.NoError(t, err)
+		require.Equal(t, `{"kind":200,"name":"server","file":"test.txt",`+
+			`"begin":[0,1,1],"end":[3,1,4],"src":"a:1","elements":[`+
+			`{"kind":3,"name":"word","begin":[0,1,1],"end":[1,1,2],`+
+			`"label":"name","src":"a"},`+
+			`{"kind":0,"begin":[1,1,2],"end":[2,1,3],"src":":"},`+
+			`{"kind":201,"name":"number","begin":[2,1,3],"end":[3,1,4],`+
+			`"label":"port","src":"1"}]}`,
+			string(data),
+		)
+	})
+}
+
+func TestUnmarshalFragment(t *testing.T) {
+	pr := newParser(t, newFunctionGrammar(), nil)
+	src := newSource("fn f{a \\{b}}\nfn g{c}")
+	mainFrag := mustParse(t, pr, src)
+
+	for name, options := range map[string]llp.JSONOptions{
+		"Default": {},
+		"Compact": {Compact: true, Src: true},
+		"Names":   {Kinds: newKinds(t)},
+	} {
+		t.Run(name, func(t *testing.T) {
+			data, err := llp.MarshalFragment(mainFrag, options)
+			require.NoError(t, err)
+
+			options.Files = func(name string) *llp.SourceFile {
+				require.Equal(t, "test.txt", name)
+				return src
+			}
+			decoded, err := llp.UnmarshalFragment(data, options)
+			require.NoError(t, err)
+			requireSameTree(t, mainFrag, decoded)
+			require.Equal(t, src, decoded.Begin().File)
+		})
+	}
+
+	t.Run("Labels", func(t *testing.T) {
+		server, err := newParser(t, newServerGrammar(), nil).Parse(
+			newSource("srv:80[a,b]"),
+		)
+		require.NoError(t, err)
+		data, err := llp.MarshalFragment(server, llp.JSONOptions{Src: true})
+		require.NoError(t, err)
+		decoded, err := llp.UnmarshalFragment(data, llp.JSONOptions{})
+		require.NoError(t, err)
+		requireSameTree(t, server, decoded)
+		require.Equal(t, server.(*llp.Construct).VLabels,
+			decoded.(*llp.Construct).VLabels,
+		)
+	})
+
+	t.Run("Reconstructed", func(t *testing.T) {
+		// The source file is reconstructed from the root fragment
+		data, err := llp.MarshalFragment(mainFrag, llp.JSONOptions{Src: true})
+		require.NoError(t, err)
+		decoded, err := llp.UnmarshalFragment(data, llp.JSONOptions{})
+		require.NoError(t, err)
+		requireSameTree(t, mainFrag, decoded)
+		require.Equal(t, "test.txt", decoded.Begin().File.Name)
+	})
+
+	t.Run("Kind names", func(t *testing.T) {
+		// Kind names take precedence over kind numbers
+		data, err := llp.MarshalFragment(mainFrag, llp.JSONOptions{
+			Kinds: newKinds(t),
+			Src:   true,
+		})
+		require.NoError(t, err)
+		renumbered := &llp.KindRegistry{}
+		require.NoError(t, renumbered.Register(42, "function"))
+		decoded, err := llp.UnmarshalFragment(data, llp.JSONOptions{
+			Kinds: renumbered,
+		})
+		require.NoError(t, err)
+		require.Equal(t, llp.FragmentKind(42), decoded.Elements()[0].Kind())
+		require.Equal(t, kindBody, decoded.Elements()[0].Elements()[3].Kind())
+	})
+}
+
+func TestUnmarshalFragmentErr(t *testing.T) {
+	for name, test := range map[string]struct {
+		Data     string
+		Expected string
+	}{
+		"Unknown file": {
+			`{"kind":1,"file":"x","begin":[0,1,1],"end":[0,1,1]}`,
+			`unknown source file "x"`,
+		},
+		"Missing file": {
+			`{"kind":1,"begin":[0,1,1],"end":[0,1,1]}`,
+			"missing source file of the root fragment",
+		},
+		"Out of range": {
+			`{"kind":1,"file":"x","begin":[0,1,1],"end":[3,1,4],"src":"ab"}`,
+			`fragment span 0-3 out of range of source file "x"`,
+		},
+		"Invalid cursor": {
+			`{"kind":1,"file":"x","begin":[0,1],"end":[0,1,1]}`,
+			"invalid cursor: [0,1]",
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			frag, err := llp.UnmarshalFragment(
+				[]byte(test.Data),
+				llp.JSONOptions{},
+			)
+			require.Error(t, err)
+			require.Equal(t, test.Expected, err.Error())
+			require.Nil(t, frag)
+		})
+	}
+}
+
+func TestJSONStream(t *testing.T) {
+	pr := newParser(t, newFunctionGrammar(), nil)
+	trees := []llp.Fragment{
+		mustParse(t, pr, newSource("fn f{a}")),
+		mustParse(t, pr, newSource("fn g{\\{b} c}\n")),
+	}
+
+	var buf bytes.Buffer
+	enc := llp.NewJSONEncoder(&buf, llp.JSONOptions{Src: true, Compact: true})
+	for _, tree := range trees {
+		require.NoError(t, enc.Encode(tree))
+	}
+
+	dec := llp.NewJSONDecoder(&buf, llp.JSONOptions{})
+	for _, tree := range trees {
+		decoded, err := dec.Decode()
+		require.NoError(t, err)
+		requireSameTree(t, tree, decoded)
+	}
+	_, err := dec.Decode()
+	require.Equal(t, io.EOF, err)
+}
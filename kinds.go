@@ -0,0 +1,155 @@
+package parser
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+)
+
+// KindRegistry represents a registry of fragment kind names.
+// The zero value of a KindRegistry is ready to use
+type KindRegistry struct {
+	names      map[FragmentKind]string
+	kinds      map[string]FragmentKind
+	categories map[FragmentKind]string
+}
+
+// Register registers the name of the given kind.
+// Returns an error if either the kind or the name is already registered
+func (kr *KindRegistry) Register(kind FragmentKind, name string) error {
+	if name == "" {
+		return fmt.Errorf("missing name of kind %d", kind)
+	}
+	if kr.names == nil {
+		kr.names = map[FragmentKind]string{}
+		kr.kinds = map[string]FragmentKind{}
+	}
+	if registered, ok := kr.names[kind]; ok {
+		return fmt.Errorf("kind %d already registered as %q", kind, registered)
+	}
+	if registered, ok := kr.kinds[name]; ok {
+		return fmt.Errorf(
+			"name %q already registered for kind %d",
+			name,
+			registered,
+		)
+	}
+	kr.names[kind] = name
+	kr.kinds[name] = kind
+	return nil
+}
+
+// Name returns the registered name of the given kind
+func (kr *KindRegistry) Name(kind FragmentKind) (string, bool) {
+	if kr == nil {
+		return "", false
+	}
+	name, ok := kr.names[kind]
+	return name, ok
+}
+
+// Kind returns the kind registered under the given name
+func (kr *KindRegistry) Kind(name string) (FragmentKind, bool) {
+	if kr == nil {
+		return 0, false
+	}
+	kind, ok := kr.kinds[name]
+	return kind, ok
+}
+
+// DefaultKinds defines the kind names used when no kind registry is
+// specified explicitly such as by Token.String, PrintFragment,
+// MarshalFragment, CompileQuery and parsers without Parser.Kinds.
+//
+// WARNING: DefaultKinds isn't synchronized and shall therefore only be set
+// during package initialization. Neither the variable nor the registry it
+// refers to may be changed while parsing or printing!
+var DefaultKinds *KindRegistry
+
+// MustRegister is like Register but panics if the kind
+// can't be registered
+func (kr *KindRegistry) MustRegister(kind FragmentKind, name string) {
+	if err := kr.Register(kind, name); err != nil {
+		panic(err)
+	}
+}
+
+// Categorize assigns the kind to a category replacing
+// any previously assigned category
+func (kr *KindRegistry) Categorize(kind FragmentKind, category string) {
+	if kr.categories == nil {
+		kr.categories = map[FragmentKind]string{}
+	}
+	kr.categories[kind] = category
+}
+
+// Category returns the category of the given kind
+func (kr *KindRegistry) Category(kind FragmentKind) (string, bool) {
+	if kr == nil {
+		return "", false
+	}
+	category, ok := kr.categories[kind]
+	return category, ok
+}
+
+// InCategory returns all kinds of the given category in ascending order
+func (kr *KindRegistry) InCategory(category string) []FragmentKind {
+	if kr == nil {
+		return nil
+	}
+	var kinds []FragmentKind
+	for kind, cat := range kr.categories {
+		if cat == category {
+			kinds = append(kinds, kind)
+		}
+	}
+	sort.Slice(kinds, func(i, j int) bool { return kinds[i] < kinds[j] })
+	return kinds
+}
+
+// nameOf returns the registered name of the given kind
+// or its number if it has no registered name
+func (kr *KindRegistry) nameOf(kind FragmentKind) string {
+	if name, ok := kr.Name(kind); ok {
+		return name
+	}
+	switch kind {
+	case KindError:
+		return "error"
+	case KindMissing:
+		return "missing"
+	}
+	return strconv.Itoa(int(kind))
+}
+
+// or returns the registry or the default one if it's nil
+func (kr *KindRegistry) or() *KindRegistry {
+	if kr == nil {
+		return DefaultKinds
+	}
+	return kr
+}
+
+// designation returns the designation of the pattern falling back to
+// the kind name of undesignated rules and lexed patterns
+func designation(pattern Pattern, kinds *KindRegistry) string {
+	var kind FragmentKind
+	switch pt := pattern.(type) {
+	case *Rule:
+		if pt.Designation != "" {
+			return pt.Designation
+		}
+		kind = pt.Kind
+	case *Lexed:
+		if pt.Designation != "" {
+			return pt.Designation
+		}
+		kind = pt.Kind
+	default:
+		return pattern.Desig()
+	}
+	if name, ok := kinds.Name(kind); ok {
+		return name
+	}
+	return ""
+}
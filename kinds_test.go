@@ -0,0 +1,99 @@
+package parser_test
+
+import (
+	"bytes"
+	"testing"
+
+	llp "github.com/romshark/llparser"
+	"github.com/stretchr/testify/require"
+)
+
+func TestKindCategories(t *testing.T) {
+	kinds := newKinds(t)
+	kinds.Categorize(FrWord, "token")
+	kinds.Categorize(FrSeparator, "token")
+	kinds.Categorize(kindPair, "construct")
+
+	category, ok := kinds.Category(FrWord)
+	require.True(t, ok)
+	require.Equal(t, "token", category)
+	_, ok = kinds.Category(FrBar)
+	require.False(t, ok)
+
+	require.Equal(
+		t,
+		[]llp.FragmentKind{FrSeparator, FrWord},
+		kinds.InCategory("token"),
+	)
+	require.Nil(t, kinds.InCategory("unknown"))
+
+	var nilKinds *llp.KindRegistry
+	_, ok = nilKinds.Category(FrWord)
+	require.False(t, ok)
+	require.Panics(t, func() { kinds.MustRegister(FrWord, "other") })
+}
+
+func TestKindNames(t *testing.T) {
+	pr := newParser(t, newPairGrammar(), nil)
+	pr.Kinds = newKinds(t)
+
+	t.Run("Error", func(t *testing.T) {
+		_, err := pr.Parse(newSource("a,;"))
+		require.Error(t, err)
+		require.Equal(
+			t,
+			"unexpected token, expected {word} at test.txt:1:3",
+			err.Error(),
+		)
+	})
+
+	t.Run("Debug", func(t *testing.T) {
+		profile, _, err := pr.Debug(newSource("a,b"))
+		require.NoError(t, err)
+		require.Equal(t, ""+
+			"test.txt:1:1 rule (pair) matched\n"+
+			". test.txt:1:1 sequence matched\n"+
+			". . test.txt:1:1 lexed (word) matched\n"+
+			". . test.txt:1:2 exact ',' (separator) matched\n"+
+			". . test.txt:1:3 lexed (word) matched\n",
+			profile.String(),
+		)
+	})
+
+	t.Run("Default", func(t *testing.T) {
+		mainFrag, err := pr.Parse(newSource("a,b"))
+		require.NoError(t, err)
+		require.Equal(t,
+			"500 (test.txt: 1:1-1:4 'a,b')",
+			mainFrag.(*llp.Construct).String(),
+		)
+
+		llp.DefaultKinds = pr.Kinds
+		defer func() { llp.DefaultKinds = nil }()
+		require.Equal(t,
+			"pair (test.txt: 1:1-1:4 'a,b')",
+			mainFrag.(*llp.Construct).String(),
+		)
+
+		var buf bytes.Buffer
+		_, err = llp.PrintFragment(mainFrag, llp.FragPrintOptions{
+			Out:         &buf,
+			Indentation: []byte(" "),
+		})
+		require.NoError(t, err)
+		require.Equal(t, ""+
+			"pair (test.txt: 1:1-1:4 'a,b') {\n"+
+			" word (test.txt: 1:1-1:2 'a')\n"+
+			" separator (test.txt: 1:2-1:3 ',')\n"+
+			" word (test.txt: 1:3-1:4 'b')\n"+
+			"}",
+			buf.String(),
+		)
+
+		data, err := llp.MarshalFragment(mainFrag, llp.JSONOptions{
+			Compact: true,
+		})
+		require.NoError(t, err)
+		require.Contains(t, string(data), `"kind":500,"name":"pair"`)
+	})
+}
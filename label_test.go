@@ -0,0 +1,103 @@
+package parser_test
+
+import (
+	"testing"
+
+	llp "github.com/romshark/llparser"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLabel(t *testing.T) {
+	pr := newParser(t, &llp.Rule{
+		Designation: "assignment",
+		Kind:        100,
+		Pattern: llp.Sequence{
+			llp.Label{Name: "target", Pattern: termLatinWord},
+			&llp.Exact{Expectation: []rune("=")},
+			llp.Label{Name: "values", Pattern: &llp.Repeated{
+				Min: 1,
+				Pattern: llp.Sequence{
+					llp.Label{Name: "value", Pattern: testR_foo},
+					llp.Label{Name: "separator", Pattern: &llp.Repeated{
+						Max:     1,
+						Pattern: termSeparator,
+					}},
+				},
+			}},
+		},
+	}, nil)
+
+	src := newSource("x=foo,foo")
+	mainFrag, err := pr.Parse(src)
+	require.NoError(t, err)
+	require.IsType(t, &llp.Construct{}, mainFrag)
+	ct := mainFrag.(*llp.Construct)
+	checkFrag(t, src, ct, 100, C{1, 1}, C{1, 10}, 5)
+
+	target := ct.Child("target")
+	require.NotNil(t, target)
+	checkFrag(t, src, target, FrWord, C{1, 1}, C{1, 2}, 0)
+
+	// Inner labels are kept
+	require.Len(t, ct.Children("values"), 0)
+
+	values := ct.Children("value")
+	require.Len(t, values, 2)
+	checkFrag(t, src, values[0], FrFoo, C{1, 3}, C{1, 6}, 1)
+	checkFrag(t, src, values[1], FrFoo, C{1, 7}, C{1, 10}, 1)
+
+	separators := ct.Children("separator")
+	require.Len(t, separators, 1)
+	checkFrag(t, src, separators[0], FrSeparator, C{1, 6}, C{1, 7}, 0)
+
+	require.Equal(t, "target", ct.Label(0))
+	require.Equal(t, "", ct.Label(1))
+	require.Equal(t, "value", ct.Label(2))
+	require.Equal(t, "separator", ct.Label(3))
+	require.Equal(t, "value", ct.Label(4))
+	require.Equal(t, "", ct.Label(5))
+
+	require.Nil(t, ct.Child("inexistent"))
+	require.Len(t, ct.Children("inexistent"), 0)
+}
+
+func TestLabelNone(t *testing.T) {
+	pr := newParser(t, &llp.Rule{
+		Designation: "foobar",
+		Pattern:     llp.Sequence{testR_foo, testR_bar},
+	}, nil)
+
+	mainFrag, err := pr.Parse(newSource("foobar"))
+	require.NoError(t, err)
+	ct := mainFrag.(*llp.Construct)
+	require.Nil(t, ct.VLabels)
+	require.Equal(t, "", ct.Label(0))
+	require.Nil(t, ct.Child("foo"))
+}
+
+func TestLabelBacktracking(t *testing.T) {
+	pr := newParser(t, &llp.Rule{
+		Designation: "main",
+		Pattern: llp.Either{
+			llp.Sequence{
+				llp.Label{Name: "first", Pattern: testR_foo},
+				&llp.Exact{Expectation: []rune("!")},
+			},
+			llp.Sequence{
+				testR_foo,
+				llp.Label{Name: "second", Pattern: &llp.Exact{
+					Expectation: []rune("?"),
+				}},
+			},
+		},
+	}, nil)
+
+	src := newSource("foo?")
+	mainFrag, err := pr.Parse(src)
+	require.NoError(t, err)
+	ct := mainFrag.(*llp.Construct)
+	require.Len(t, ct.Elements(), 2)
+	require.Nil(t, ct.Child("first"))
+	require.Equal(t, "", ct.Label(0))
+	require.Equal(t, "?", string(ct.Child("second").Src()))
+}
@@ -8,7 +8,14 @@ import (
 // into 3 basic categories: spaces (whitespaces, tabs, line-breaks),
 // signs (any ASCII special character) and
 // words (any other character)
-type lexer struct{ cr Cursor }
+type lexer struct {
+	cr Cursor
+
+	// examined is the index following the last examined rune
+	// where examining the end of the file counts as examining
+	// the rune following the last one
+	examined uint
+}
 
 // newLexer creates a new basic-latin lexer instance
 func newLexer(src *SourceFile) *lexer {
@@ -32,7 +39,36 @@ func finalizedToken(
 }
 
 func (lx *lexer) reachedEOF() bool {
-	return lx.cr.Index >= uint(len(lx.cr.File.Src))
+	if lx.cr.Index >= uint(len(lx.cr.File.Src)) {
+		lx.examine(uint(len(lx.cr.File.Src)))
+		return true
+	}
+	return false
+}
+
+// examine records the rune at the given index as examined
+func (lx *lexer) examine(index uint) {
+	if index >= lx.examined {
+		lx.examined = index + 1
+	}
+}
+
+// advance advances the cursor by one rune
+// moving it to the next line after line-breaks
+func (lx *lexer) advance() {
+	src := lx.cr.File.Src
+	if src[lx.cr.Index] == '\r' {
+		// Whether a carriage return ends the line
+		// depends on the following rune
+		lx.examine(lx.cr.Index + 1)
+	}
+	if lx.cr.File.LineEndings.endsLine(src, lx.cr.Index) {
+		lx.cr.Column = 1
+		lx.cr.Line++
+	} else {
+		lx.cr.Column++
+	}
+	lx.cr.Index++
 }
 
 // ReadExact tries to read an exact string and returns false if
@@ -64,17 +100,9 @@ func (lx *lexer) ReadExact(
 
 		// Check against the expectation
 		rn := lx.cr.File.Src[lx.cr.Index]
+		lx.examine(lx.cr.Index)
 
-		// Advance the cursor
-		switch rn {
-		case '\n':
-			lx.cr.Index++
-			lx.cr.Column = 1
-			lx.cr.Line++
-		default:
-			lx.cr.Index++
-			lx.cr.Column++
-		}
+		lx.advance()
 
 		if rn != expectation[ix] {
 			// No match
@@ -101,17 +129,15 @@ func (lx *lexer) ReadUntil(
 	subLexerIndex := uint(0)
 
 	for {
-		if lx.reachedEOF() || !fn(subLexerIndex, lx.cr) {
+		if lx.reachedEOF() {
 			break
 		}
-		if lx.cr.File.Src[lx.cr.Index] == '\n' {
-			lx.cr.Column = 1
-			lx.cr.Line++
-		} else {
-			lx.cr.Column++
+		lx.examine(lx.cr.Index)
+		if !fn(subLexerIndex, lx.cr) {
+			break
 		}
+		lx.advance()
 		subLexerIndex++
-		lx.cr.Index++
 	}
 
 	return finalizedToken(token, lx.cr), nil
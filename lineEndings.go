@@ -0,0 +1,125 @@
+package parser
+
+// LineEndings defines the sequences recognized as line-breaks.
+// The zero value recognizes LF only
+type LineEndings uint8
+
+const (
+	// LineEndLF recognizes "\n"
+	LineEndLF LineEndings = 1 << iota
+
+	// LineEndCRLF recognizes "\r\n"
+	LineEndCRLF
+
+	// LineEndCR recognizes "\r" unless it's part of a recognized "\r\n"
+	LineEndCR
+
+	// LineEndUnicode recognizes the next-line (U+0085), line separator
+	// (U+2028) and paragraph separator (U+2029) characters
+	LineEndUnicode
+
+	// LineEndAny recognizes all line-breaks
+	LineEndAny = LineEndLF | LineEndCRLF | LineEndCR | LineEndUnicode
+)
+
+func (le LineEndings) or() LineEndings {
+	if le == 0 {
+		return LineEndLF
+	}
+	return le
+}
+
+// breakLen returns the number of runes of the line-break
+// beginning at the given index or 0 if there's none
+func (le LineEndings) breakLen(src []rune, index uint) uint {
+	le = le.or()
+	switch src[index] {
+	case '\n':
+		if le&LineEndLF != 0 {
+			return 1
+		}
+	case '\r':
+		if le&LineEndCRLF != 0 &&
+			index+1 < uint(len(src)) &&
+			src[index+1] == '\n' {
+			return 2
+		}
+		if le&LineEndCR != 0 {
+			return 1
+		}
+	case '\u0085', '\u2028', '\u2029':
+		if le&LineEndUnicode != 0 {
+			return 1
+		}
+	}
+	return 0
+}
+
+// endsLine returns true if the rune at the given index
+// is the last rune of a line-break
+func (le LineEndings) endsLine(src []rune, index uint) bool {
+	if index > 0 && src[index] == '\n' && src[index-1] == '\r' &&
+		le.or()&LineEndCRLF != 0 {
+		return true
+	}
+	return le.breakLen(src, index) == 1
+}
+
+// withLineEndings returns the file if it recognizes the given line-breaks
+// or a copy of it recognizing them otherwise leaving the file unmodified
+func withLineEndings(file *SourceFile, endings LineEndings) *SourceFile {
+	if file.LineEndings.or() == endings.or() {
+		return file
+	}
+	return &SourceFile{
+		Name:        file.Name,
+		Src:         file.Src,
+		TabWidth:    file.TabWidth,
+		LineEndings: endings,
+		LineBreaks:  file.LineBreaks,
+	}
+}
+
+// NormalizeLineEndings returns a copy of the file replacing all
+// recognized line-breaks by "\n" and recording the original ones
+// in LineBreaks to allow restoring them (see RestoreLineEndings)
+func NormalizeLineEndings(file *SourceFile, endings LineEndings) *SourceFile {
+	normalized := &SourceFile{
+		Name:        file.Name,
+		Src:         make([]rune, 0, len(file.Src)),
+		TabWidth:    file.TabWidth,
+		LineEndings: LineEndLF,
+		LineBreaks:  []string{},
+	}
+	for ix := uint(0); ix < uint(len(file.Src)); ix++ {
+		ln := endings.breakLen(file.Src, ix)
+		if ln < 1 {
+			normalized.Src = append(normalized.Src, file.Src[ix])
+			continue
+		}
+		normalized.Src = append(normalized.Src, '\n')
+		normalized.LineBreaks = append(
+			normalized.LineBreaks,
+			string(file.Src[ix:ix+ln]),
+		)
+		ix += ln - 1
+	}
+	return normalized
+}
+
+// RestoreLineEndings replaces the line-breaks of the given source code
+// of a normalized file by the original line-breaks in order.
+// Line-breaks exceeding the recorded ones are kept as is
+func (f *SourceFile) RestoreLineEndings(src []rune) []rune {
+	restored := make([]rune, 0, len(src))
+	breaks := 0
+	for _, rn := range src {
+		if rn != '\n' || breaks >= len(f.LineBreaks) {
+			restored = append(restored, rn)
+			continue
+		}
+		restored = append(restored, []rune(f.LineBreaks[breaks])...)
+		breaks++
+	}
+	return restored
+}
@@ -0,0 +1,132 @@
+package parser_test
+
+import (
+	"testing"
+	"unicode"
+
+	llp "github.com/romshark/llparser"
+	"github.com/stretchr/testify/require"
+)
+
+// newLinesGrammar creates a grammar of "x" words and "y" keywords
+// separated by spaces and line-breaks
+func newLinesGrammar() *llp.Rule {
+	return &llp.Rule{
+		Designation: "lines",
+		Pattern: &llp.Repeated{Pattern: llp.Either{
+			&llp.Exact{Kind: FrFoo, Expectation: []rune("y")},
+			&llp.Lexed{
+				Kind: FrWord,
+				Fn: func(_ uint, cr llp.Cursor) bool {
+					return cr.File.Src[cr.Index] == 'x'
+				},
+			},
+			&llp.Lexed{
+				Kind: FrSpace,
+				Fn: func(_ uint, cr llp.Cursor) bool {
+					rn := cr.File.Src[cr.Index]
+					return unicode.IsSpace(rn) || rn == '\u2028'
+				},
+			},
+		}},
+	}
+}
+
+func TestLineEndings(t *testing.T) {
+	const src = "x\r\ny\rx\nx\u2028y"
+	for name, test := range map[string]struct {
+		LineEndings llp.LineEndings
+		Expected    []C
+	}{
+		"Default": {0, []C{{1, 1}, {2, 1}, {2, 3}, {3, 1}, {3, 3}}},
+		"CRLF": {
+			llp.LineEndCRLF,
+			[]C{{1, 1}, {2, 1}, {2, 3}, {2, 5}, {2, 7}},
+		},
+		"Any": {
+			llp.LineEndAny,
+			[]C{{1, 1}, {2, 1}, {3, 1}, {4, 1}, {5, 1}},
+		},
+		"LF and CR": {
+			llp.LineEndLF | llp.LineEndCR,
+			[]C{{1, 1}, {3, 1}, {4, 1}, {5, 1}, {5, 3}},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			pr := newParser(t, newLinesGrammar(), nil)
+			pr.LineEndings = test.LineEndings
+			file := newSource(src)
+			mainFrag, err := pr.Parse(file)
+			require.NoError(t, err)
+
+			// The parsed file is never modified, the cursors refer
+			// to a copy recognizing the line endings of the parser
+			require.Equal(t, llp.LineEndings(0), file.LineEndings)
+			parsed := mainFrag.Begin().File
+			if test.LineEndings == 0 {
+				require.True(t, parsed == file)
+			} else {
+				require.Equal(t, test.LineEndings, parsed.LineEndings)
+			}
+
+			words := llp.FindKinds(mainFrag, FrWord, FrFoo)
+			require.Len(t, words, len(test.Expected))
+			for ix, word := range words {
+				CheckCursor(
+					t,
+					parsed,
+					word.Begin(),
+					test.Expected[ix].line,
+					test.Expected[ix].column,
+				)
+
+				// The line index of the file agrees with the lexer
+				cr, err := parsed.CursorAt(word.Begin().Index)
+				require.NoError(t, err)
+				require.Equal(t, word.Begin(), cr)
+			}
+		})
+	}
+}
+
+func TestLineEndingsLines(t *testing.T) {
+	file := newSource("a\r\nb\rc\n")
+	file.LineEndings = llp.LineEndAny
+	require.Equal(t, uint(4), file.LineCount())
+	for line, expected := range []string{"a", "b", "c", ""} {
+		text, err := file.Line(uint(line + 1))
+		require.NoError(t, err)
+		require.Equal(t, expected, string(text))
+	}
+
+	// Carriage returns are part of the line when CRLF isn't recognized
+	file.LineEndings = llp.LineEndLF
+	text, err := file.Line(1)
+	require.NoError(t, err)
+	require.Equal(t, "a\r", string(text))
+}
+
+func TestNormalizeLineEndings(t *testing.T) {
+	const src = "x\r\ny\rx\nx\u2028y\r\n"
+	pr := newParser(t, newLinesGrammar(), nil)
+	pr.LineEndings = llp.LineEndAny
+	result, err := pr.ParseWith(newSource(src), llp.ParseOptions{
+		Lossless:             true,
+		NormalizeLineEndings: true,
+	})
+	require.NoError(t, err)
+
+	file := result.Fragment.Begin().File
+	require.Equal(t, "x\ny\nx\nx\ny\n", string(file.Src))
+	require.Equal(t, "test.txt", file.Name)
+	require.Equal(
+		t,
+		[]string{"\r\n", "\r", "\n", "\u2028", "\r\n"},
+		file.LineBreaks,
+	)
+	CheckCursor(t, file, result.Fragment.End(), 6, 1)
+
+	// Reprinting restores the original line-breaks
+	reprinted := llp.Reprint(result.Fragment)
+	require.Equal(t, src, string(file.RestoreLineEndings([]rune(reprinted))))
+}
@@ -0,0 +1,62 @@
+package parser
+
+import (
+	"errors"
+	"strings"
+)
+
+// isToken returns true for terminal fragments
+func isToken(frag Fragment) bool {
+	if _, ok := frag.(*Construct); ok {
+		return false
+	}
+	return len(frag.Elements()) < 1
+}
+
+// appendTokens appends all tokens of the fragment in document order
+func appendTokens(tokens []Fragment, fragment Fragment) []Fragment {
+	Inspect(fragment, func(frag Fragment) bool {
+		if isToken(frag) {
+			tokens = append(tokens, frag)
+		}
+		return true
+	})
+	return tokens
+}
+
+// Tokens returns all tokens of the fragment in document order
+func Tokens(fragment Fragment) []Fragment {
+	return appendTokens(nil, fragment)
+}
+
+// VerifyLossless verifies that every rune of the fragment is covered by
+// exactly one token in document order. Verifying the main fragment of a
+// parse-tree verifies the entire source file.
+// Returns an *Err pointing to the first violation
+func VerifyLossless(fragment Fragment) error {
+	pos := fragment.Begin()
+	for _, tk := range Tokens(fragment) {
+		switch begin := tk.Begin(); {
+		case begin.Index > pos.Index:
+			return &Err{Err: errors.New("runes not covered by any token"), At: pos}
+		case begin.Index < pos.Index:
+			return &Err{Err: errors.New("overlapping token"), At: begin}
+		}
+		pos = tk.End()
+	}
+	if pos.Index != fragment.End().Index {
+		return &Err{Err: errors.New("runes not covered by any token"), At: pos}
+	}
+	return nil
+}
+
+// Reprint returns the source code of the fragment reconstructed from its
+// tokens. The result is identical to the original source code of the
+// fragment if the fragment is lossless (see VerifyLossless)
+func Reprint(fragment Fragment) string {
+	var str strings.Builder
+	for _, tk := range Tokens(fragment) {
+		str.WriteString(string(tk.Src()))
+	}
+	return str.String()
+}
@@ -0,0 +1,86 @@
+package parser_test
+
+import (
+	"testing"
+
+	llp "github.com/romshark/llparser"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLossless(t *testing.T) {
+	pr := newParser(t, newCalcGrammar(), nil)
+	src := newSource("2*(3+4)*5+1")
+
+	t.Run("Hidden", func(t *testing.T) {
+		result, err := pr.ParseWith(src, llp.ParseOptions{})
+		require.NoError(t, err)
+
+		err = llp.VerifyLossless(result.Fragment)
+		require.Error(t, err)
+		require.IsType(t, &llp.Err{}, err)
+		require.Equal(
+			t,
+			"runes not covered by any token at test.txt:1:2",
+			err.Error(),
+		)
+		require.Equal(t, "23451", llp.Reprint(result.Fragment))
+	})
+
+	t.Run("Lossless", func(t *testing.T) {
+		result, err := pr.ParseWith(src, llp.ParseOptions{Lossless: true})
+		require.NoError(t, err)
+		require.NoError(t, llp.VerifyLossless(result.Fragment))
+		require.Equal(t, string(src.Src), llp.Reprint(result.Fragment))
+
+		// Semantic values are unaffected
+		require.Equal(t, 71, result.Value)
+	})
+}
+
+func TestLosslessInvariant(t *testing.T) {
+	for name, test := range map[string]struct {
+		Grammar *llp.Rule
+		Src     string
+	}{
+		"Calc":    {newCalcGrammar(), "(1+2)*3+4*(5)"},
+		"Symbols": {newSymbolGrammar(), "$a;{$b;a;b;}$c!"},
+		"Server":  {newServerGrammar(), "srv:8080:true[a,b]"},
+		"Cut":     {newCutGrammar(true), "foo,func bar(),"},
+		"Either":  {newCutGrammar(false), "a,b,"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			pr := newParser(t, test.Grammar, nil)
+			result, err := pr.ParseWith(
+				newSource(test.Src),
+				llp.ParseOptions{Lossless: true},
+			)
+			require.NoError(t, err)
+			require.NoError(t, llp.VerifyLossless(result.Fragment))
+			require.Equal(t, test.Src, llp.Reprint(result.Fragment))
+		})
+	}
+}
+
+func TestVerifyLosslessOverlap(t *testing.T) {
+	src := newSource("abc")
+	mainFrag := &llp.Construct{
+		Token: &llp.Token{
+			VBegin: llp.Cursor{File: src, Index: 0, Line: 1, Column: 1},
+			VEnd:   llp.Cursor{File: src, Index: 3, Line: 1, Column: 4},
+		},
+		VElements: []llp.Fragment{
+			&llp.Token{
+				VBegin: llp.Cursor{File: src, Index: 0, Line: 1, Column: 1},
+				VEnd:   llp.Cursor{File: src, Index: 2, Line: 1, Column: 3},
+			},
+			&llp.Token{
+				VBegin: llp.Cursor{File: src, Index: 1, Line: 1, Column: 2},
+				VEnd:   llp.Cursor{File: src, Index: 3, Line: 1, Column: 4},
+			},
+		},
+	}
+	err := llp.VerifyLossless(mainFrag)
+	require.Error(t, err)
+	require.Equal(t, "overlapping token at test.txt:1:2", err.Error())
+	require.Len(t, llp.Tokens(mainFrag), 2)
+}
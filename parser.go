@@ -12,9 +12,40 @@ type Parser struct {
 	errGrammar        *Rule
 	recursionRegister recursionRegister
 
+	// reducing is true when any rule of the grammar has a reducer
+	reducing bool
+
+	// reusable holds the rules whose matches can be reused by Reparse
+	reusable map[*Rule]bool
+
+	// memo holds the rule matches of the latest incremental parse
+	memo *memo
+
 	// MaxRecursionLevel defines the maximum tolerated recursion level.
 	// The limitation is disabled when MaxRecursionLevel is set to 0
 	MaxRecursionLevel uint
+
+	// DeferActions defers the execution of rule actions until the parse
+	// succeeds. Deferred actions are only executed for fragments that are
+	// part of the final parse-tree in the order they were matched in
+	DeferActions bool
+
+	// Incremental records the rule matches of the latest parse
+	// allowing Reparse to reuse them
+	Incremental bool
+
+	// LineEndings defines the line-breaks recognized by the cursors.
+	// Source files recognizing other line-breaks are parsed as copies
+	// recognizing these, the parsed source files are never modified
+	LineEndings LineEndings
+
+	// Kinds optionally defines the kind names used in errors
+	// and debug profiles. DefaultKinds is used when nil
+	Kinds *KindRegistry
+
+	// ErrorStacks includes the rule stacks recorded by Err and
+	// ErrUnexpectedToken in their messages, which is useful for debugging
+	ErrorStacks bool
 }
 
 // NewParser creates a new parser instance
@@ -35,10 +66,25 @@ func NewParser(grammar *Rule, errGrammar *Rule) (*Parser, error) {
 	findRules(grammar, recRegister)
 	findRules(errGrammar, recRegister)
 
+	reducing := false
+	for rule := range recRegister {
+		if rule.Reduce != nil {
+			reducing = true
+			break
+		}
+	}
+
+	reusable := map[*Rule]bool{}
+	for rule := range recRegister {
+		reusable[rule] = pureRule(rule)
+	}
+
 	return &Parser{
 		grammar:           grammar,
 		errGrammar:        errGrammar,
 		recursionRegister: recRegister,
+		reducing:          reducing,
+		reusable:          reusable,
 
 		// Disable recursion limitation by default
 		MaxRecursionLevel: uint(0),
@@ -47,35 +93,55 @@ func NewParser(grammar *Rule, errGrammar *Rule) (*Parser, error) {
 
 func (pr Parser) handlePattern(
 	debug *DebugProfile,
+	ctx *Context,
 	scan *scanner,
 	pattern Pattern,
 	level uint,
 ) (frag Fragment, err error) {
 	switch pt := pattern.(type) {
 	case *Rule:
-		frag, err = pr.parseRule(debug, scan.New(), pt, level)
-		if err, ok := err.(*ErrUnexpectedToken); ok {
+		sub := scan.New()
+		frag, err = pr.parseRule(debug, ctx, sub, pt, level)
+		if err == nil && pt.Shape != ShapeHidden {
+			scan.AppendValues(sub.Result...)
+		}
+		if err, ok := err.(*ErrUnexpectedToken); ok &&
+			!err.committed && !ctx.committed() {
 			// Override expected pattern to the higher-order rule
-			err.Expected = pt
+			// unless the error occurred after a cut
+			err.expect(pt, sub.Begin)
 		}
 
 	case *Exact:
-		frag, err = pr.parseExact(debug, scan, pt, level)
+		frag, err = pr.parseExact(debug, ctx, scan, pt, level)
 
 	case *Lexed:
-		frag, err = pr.parseLexed(debug, scan, pt, level)
+		frag, err = pr.parseLexed(debug, ctx, scan, pt, level)
 
 	case *Repeated:
-		err = pr.parseRepeated(debug, scan, pt.Min, pt.Max, pt, level)
+		err = pr.parseRepeated(debug, ctx, scan, pt.Min, pt.Max, pt, level)
 
 	case Sequence:
-		err = pr.parseSequence(debug, scan, pt, level)
+		err = pr.parseSequence(debug, ctx, scan, pt, level)
 
 	case Either:
-		frag, err = pr.parseEither(debug, scan, pt, level)
+		frag, err = pr.parseEither(debug, ctx, scan, pt, level)
 
 	case Not:
-		err = pr.parseNot(debug, scan, pt, level)
+		err = pr.parseNot(debug, ctx, scan, pt, level)
+
+	case *Predicate:
+		err = pr.parsePredicate(debug, ctx, scan, pt, level)
+
+	case Cut:
+		debug.record(pt, scan.Lexer.cr, level)
+		ctx.cut()
+
+	case Label:
+		frag, err = pr.parseLabel(debug, ctx, scan, pt, level)
+
+	case Shaped:
+		frag, err = pr.parseShaped(debug, ctx, scan, pt, level)
 
 	default:
 		panic(fmt.Errorf(
@@ -88,25 +154,31 @@ func (pr Parser) handlePattern(
 
 func (pr Parser) parseNot(
 	debug *DebugProfile,
+	ctx *Context,
 	scan *scanner,
 	ptr Not,
 	level uint,
 ) error {
 	debugIndex := debug.record(ptr, scan.Lexer.cr, level)
 
-	beforeCr := scan.Lexer.cr
-	_, err := pr.handlePattern(debug, scan, ptr.Pattern, level+1)
+	before := ctx.checkpoint(scan)
+
+	// Don't let cuts escape the negation
+	ctx.pushChoice()
+	_, err := pr.handlePattern(debug, ctx, scan, ptr.Pattern, level+1)
+	ctx.popChoice()
+
 	switch err := err.(type) {
 	case *ErrUnexpectedToken:
-		scan.Set(beforeCr)
+		ctx.backtrack(before, scan)
 		return nil
 	case errEOF:
-		scan.Set(beforeCr)
+		ctx.backtrack(before, scan)
 		return nil
 	case nil:
 		debug.markMismatch(debugIndex)
 		return &ErrUnexpectedToken{
-			At:       beforeCr,
+			At:       before.cr,
 			Expected: ptr,
 		}
 	default:
@@ -114,8 +186,77 @@ func (pr Parser) parseNot(
 	}
 }
 
+func (pr Parser) parseLabel(
+	debug *DebugProfile,
+	ctx *Context,
+	scan *scanner,
+	label Label,
+	level uint,
+) (Fragment, error) {
+	debugIndex := debug.record(label, scan.Lexer.cr, level)
+
+	begin := len(scan.Records)
+	frag, err := pr.handlePattern(debug, ctx, scan, label.Pattern, level+1)
+	if err != nil {
+		debug.markMismatch(debugIndex)
+		return nil, err
+	}
+	// Append rule patterns, other patterns are appended automatically
+	if !label.Pattern.Container() {
+		scan.Append(label.Pattern, frag)
+	}
+	scan.Label(begin, label.Name)
+	return frag, nil
+}
+
+func (pr Parser) parseShaped(
+	debug *DebugProfile,
+	ctx *Context,
+	scan *scanner,
+	shaped Shaped,
+	level uint,
+) (Fragment, error) {
+	debugIndex := debug.record(shaped, scan.Lexer.cr, level)
+
+	begin, valBegin := len(scan.Records), len(scan.Values)
+	frag, err := pr.handlePattern(debug, ctx, scan, shaped.Pattern, level+1)
+	if err != nil {
+		debug.markMismatch(debugIndex)
+		return nil, err
+	}
+	// Append rule patterns, other patterns are appended automatically
+	if !shaped.Pattern.Container() {
+		scan.Append(shaped.Pattern, frag)
+	}
+	scan.Shape(begin, shaped.Shape)
+	if shaped.Shape == ShapeHidden {
+		scan.TruncateValues(valBegin)
+	}
+	return frag, nil
+}
+
+func (pr Parser) parsePredicate(
+	debug *DebugProfile,
+	ctx *Context,
+	scan *scanner,
+	ptr *Predicate,
+	level uint,
+) error {
+	debugIndex := debug.record(ptr, scan.Lexer.cr, level)
+
+	if !ptr.Fn(ctx, scan.Lexer.cr) {
+		debug.markMismatch(debugIndex)
+		return &ErrUnexpectedToken{
+			At:       scan.Lexer.cr,
+			Expected: ptr,
+		}
+	}
+	return nil
+}
+
 func (pr Parser) parseLexed(
 	debug *DebugProfile,
+	ctx *Context,
 	scanner *scanner,
 	expected *Lexed,
 	level uint,
@@ -124,6 +265,7 @@ func (pr Parser) parseLexed(
 
 	if scanner.Lexer.reachedEOF() {
 		debug.markMismatch(debugIndex)
+		ctx.fail(scanner.Lexer.cr)
 		return nil, errEOF{}
 	}
 
@@ -132,18 +274,29 @@ func (pr Parser) parseLexed(
 	if err != nil {
 		return nil, err
 	}
+	if expected.Lookahead > 0 {
+		// Fn may have looked ahead of the rune it stopped at
+		last := scanner.Lexer.cr.Index + expected.Lookahead
+		if eof := uint(len(scanner.Lexer.cr.File.Src)); last > eof {
+			last = eof
+		}
+		scanner.Lexer.examine(last)
+	}
 	if tk == nil || tk.VEnd.Index-tk.VBegin.Index < expected.MinLen {
 		debug.markMismatch(debugIndex)
+		ctx.fail(beforeCr)
 		return nil, &ErrUnexpectedToken{
 			At:       beforeCr,
 			Expected: expected,
 		}
 	}
+	ctx.tokens++
 	return tk, nil
 }
 
 func (pr Parser) parseRepeated(
 	debug *DebugProfile,
+	ctx *Context,
 	scanner *scanner,
 	min uint,
 	max uint,
@@ -152,30 +305,73 @@ func (pr Parser) parseRepeated(
 ) error {
 	debugIndex := debug.record(repeated, scanner.Lexer.cr, level)
 
+	if ctx.tolerant() {
+		ctx.follow = append(ctx.follow, following{
+			patterns: []Pattern{repeated.Pattern},
+			repeated: true,
+		})
+		defer func() { ctx.follow = ctx.follow[:len(ctx.follow)-1] }()
+	}
+
 	num := uint(0)
-	lastPosition := scanner.Lexer.cr
+	last := ctx.checkpoint(scanner)
 	for {
 		if max != 0 && num >= max {
 			break
 		}
 
+		ctx.pushChoice()
 		frag, err := pr.handlePattern(
 			debug,
+			ctx,
 			scanner,
 			repeated.Pattern,
 			level+1,
 		)
+		cut := ctx.popChoice()
+
+		if err != nil {
+			recovered, resume := pr.recoverRepetition(
+				ctx, scanner, repeated, last, err,
+				min == 0 || num >= min, level,
+			)
+			if resume {
+				last = ctx.checkpoint(scanner)
+				continue
+			}
+			if recovered {
+				return nil
+			}
+		}
+
 		switch err := err.(type) {
 		case *ErrUnexpectedToken:
+			if cut {
+				err.committed = true
+			}
+			if err.committed {
+				// Mismatch after a cut
+				debug.markMismatch(debugIndex)
+				return err
+			}
 			if min != 0 && num < min {
 				// Mismatch before the minimum is read
 				return err
 			}
 			// Reset scanner to the last match
-			scanner.Set(lastPosition)
+			ctx.backtrack(last, scanner)
 			return nil
 
 		case errEOF:
+			if cut {
+				// Unexpected end of file after a cut
+				debug.markMismatch(debugIndex)
+				return &ErrUnexpectedToken{
+					At:        scanner.Lexer.cr,
+					Expected:  repeated.Pattern,
+					committed: true,
+				}
+			}
 			if min != 0 && num < min {
 				// Mismatch before the minimum is read
 				debug.markMismatch(debugIndex)
@@ -185,16 +381,22 @@ func (pr Parser) parseRepeated(
 				}
 			}
 			// Reset scanner to the last match
-			scanner.Set(lastPosition)
+			ctx.backtrack(last, scanner)
 			return nil
 
 		case nil:
+			if ctx.tolerant() && scanner.Lexer.cr == last.cr {
+				// Don't repeat iterations consisting of
+				// missing fragments only
+				ctx.backtrack(last, scanner)
+				return nil
+			}
 			num++
-			lastPosition = scanner.Lexer.cr
 			// Append rule patterns, other patterns are appended automatically
 			if !repeated.Pattern.Container() {
 				scanner.Append(repeated.Pattern, frag)
 			}
+			last = ctx.checkpoint(scanner)
 
 		default:
 			return err
@@ -206,15 +408,32 @@ func (pr Parser) parseRepeated(
 
 func (pr Parser) parseSequence(
 	debug *DebugProfile,
+	ctx *Context,
 	scanner *scanner,
 	patterns Sequence,
 	level uint,
 ) error {
 	debugIndex := debug.record(patterns, scanner.Lexer.cr, level)
 
-	for _, pt := range patterns {
-		frag, err := pr.handlePattern(debug, scanner, pt, level+1)
+	tolerant := ctx.tolerant()
+	if tolerant {
+		ctx.follow = append(ctx.follow, following{})
+		defer func() { ctx.follow = ctx.follow[:len(ctx.follow)-1] }()
+	}
+	for ix := 0; ix < len(patterns); ix++ {
+		pt := patterns[ix]
+		cp := ctx.checkpoint(scanner)
+		if tolerant {
+			ctx.follow[len(ctx.follow)-1].patterns = patterns[ix+1:]
+		}
+		frag, err := pr.handlePattern(debug, ctx, scanner, pt, level+1)
 		if err != nil {
+			if next, ok := pr.recoverSequence(
+				ctx, scanner, patterns, ix, cp, err, level,
+			); ok {
+				ix = next - 1
+				continue
+			}
 			debug.markMismatch(debugIndex)
 			return err
 		}
@@ -228,26 +447,43 @@ func (pr Parser) parseSequence(
 
 func (pr Parser) parseEither(
 	debug *DebugProfile,
+	ctx *Context,
 	scanner *scanner,
 	patternOptions Either,
 	level uint,
 ) (Fragment, error) {
 	debugIndex := debug.record(patternOptions, scanner.Lexer.cr, level)
 
-	beforeCr := scanner.Lexer.cr
+	before := ctx.checkpoint(scanner)
 	for ix, pt := range patternOptions {
 		lastOption := ix >= len(patternOptions)-1
 
-		frag, err := pr.handlePattern(debug, scanner, pt, level+1)
+		ctx.pushChoice()
+		frag, err := pr.handlePattern(debug, ctx, scanner, pt, level+1)
+		cut := ctx.popChoice()
+
 		if err != nil {
+			if _, ok := err.(errEOF); ok && cut {
+				// Unexpected end of file after a cut
+				err = &ErrUnexpectedToken{
+					At:       scanner.Lexer.cr,
+					Expected: pt,
+				}
+			}
 			if er, ok := err.(*ErrUnexpectedToken); ok {
-				if lastOption {
+				if cut {
+					er.committed = true
+				}
+				if er.committed {
+					// Don't try other options after a cut
+					debug.markMismatch(debugIndex)
+				} else if lastOption {
 					// Set actual expected pattern
-					er.Expected = patternOptions
+					er.expect(patternOptions, before.cr)
 					debug.markMismatch(debugIndex)
 				} else {
 					// Reset scanner to the initial position
-					scanner.Set(beforeCr)
+					ctx.backtrack(before, scanner)
 					// Continue checking other options
 					continue
 				}
@@ -268,6 +504,7 @@ func (pr Parser) parseEither(
 
 func (pr Parser) parseExact(
 	debug *DebugProfile,
+	ctx *Context,
 	scanner *scanner,
 	exact *Exact,
 	level uint,
@@ -276,6 +513,7 @@ func (pr Parser) parseExact(
 
 	if scanner.Lexer.reachedEOF() {
 		debug.markMismatch(debugIndex)
+		ctx.fail(scanner.Lexer.cr)
 		return nil, errEOF{}
 	}
 
@@ -289,16 +527,19 @@ func (pr Parser) parseExact(
 	}
 	if !match {
 		debug.markMismatch(debugIndex)
+		ctx.fail(beforeCr)
 		return nil, &ErrUnexpectedToken{
 			At:       beforeCr,
 			Expected: exact,
 		}
 	}
+	ctx.tokens++
 	return tk, nil
 }
 
 func (pr Parser) parseRule(
 	debug *DebugProfile,
+	ctx *Context,
 	scanner *scanner,
 	rule *Rule,
 	level uint,
@@ -315,14 +556,57 @@ func (pr Parser) parseRule(
 					rule,
 					rule.Designation,
 				),
-				At: scanner.Lexer.cr,
+				At:   scanner.Lexer.cr,
+				Code: CodeRecursionLimit,
+				// Include the rule exceeding the limit
+				Stack: append(ctx.stack[:len(ctx.stack):len(ctx.stack)],
+					StackFrame{Rule: rule, Begin: scanner.Lexer.cr},
+				),
 			}
 		}
 	}
 
-	frag, err = pr.handlePattern(debug, scanner, rule.Pattern, level+1)
+	if memo := scanner.Memo; memo != nil && pr.reusable[rule] &&
+		!scanner.TrackValues && pr.MaxRecursionLevel == 0 {
+		if reused, cut := memo.reuse(rule, scanner.Lexer); reused != nil {
+			if cut {
+				ctx.cut()
+			}
+			return reused, nil
+		}
+		// Record the runes examined by this rule only
+		begin, examined := scanner.Lexer.cr.Index, scanner.Lexer.examined
+		scanner.Lexer.examined = 0
+		cuts := ctx.cuts()
+		defer func() {
+			if err == nil {
+				memo.record(
+					rule, begin, frag, scanner.Lexer, ctx.cuts() > cuts,
+				)
+			}
+			if examined > scanner.Lexer.examined {
+				scanner.Lexer.examined = examined
+			}
+		}()
+	}
+
+	begin := scanner.Lexer.cr
+	ctx.pushRule(rule, begin)
+	defer ctx.popRule()
+	if rule.Scoped {
+		ctx.openScope()
+	}
+
+	frag, err = pr.handlePattern(debug, ctx, scanner, rule.Pattern, level+1)
+	if rule.Scoped {
+		ctx.closeScope()
+	}
 	if err != nil {
 		debug.markMismatch(debugIndex)
+		ctx.failRule(err)
+		if rule.ErrRule != nil {
+			pr.tryRuleErrRule(debug, ctx, scanner, rule, begin, err, level)
+		}
 		return
 	}
 	if !rule.Pattern.Container() {
@@ -330,23 +614,97 @@ func (pr Parser) parseRule(
 	}
 	frag = scanner.Fragment(rule.Kind)
 
-	if rule.Action != nil {
-		// Execute rule action callback
-		if err := rule.Action(frag); err != nil {
-			return nil, &Err{Err: err, At: frag.Begin()}
+	// Compute the semantic value of the rule
+	scanner.Result = scanner.Values
+	if rule.Reduce != nil && scanner.TrackValues {
+		values := make([]interface{}, len(scanner.Values))
+		for ix, val := range scanner.Values {
+			values[ix] = val.Value
+		}
+		val, err := rule.Reduce(ctx, frag, values)
+		if err != nil {
+			return nil, &Err{
+				Err:   err,
+				At:    frag.Begin(),
+				Code:  CodeReduce,
+				Stack: append([]StackFrame(nil), ctx.stack...),
+			}
 		}
+		scanner.Result = []value{{At: frag.Begin().Index, Value: val}}
+	}
+
+	// Execute or defer the rule action callback
+	if err := ctx.action(rule, frag); err != nil {
+		return nil, err
 	}
 	return
 }
 
+// tryRuleErrRule tries the error-rule of the failed rule at the position
+// of the rule recording the error returned by its action
+func (pr Parser) tryRuleErrRule(
+	debug *DebugProfile,
+	ctx *Context,
+	scanner *scanner,
+	rule *Rule,
+	begin Cursor,
+	failure error,
+	level uint,
+) {
+	unexpErr, ok := failure.(*ErrUnexpectedToken)
+	if _, eof := failure.(errEOF); !ok && !eof {
+		return
+	}
+	if ok && unexpErr.ruleErr != nil {
+		// The error of an inner rule takes precedence
+		return
+	}
+
+	// Match the error-rule in a detached context
+	// keeping the parse context unaffected
+	errCtx := newContext(ctx.value, false)
+	errCtx.stack = append([]StackFrame(nil), ctx.stack...)
+	errCtx.symbols = ctx.symbols[:len(ctx.symbols):len(ctx.symbols)]
+	errCtx.scopes = append([]int(nil), ctx.scopes...)
+	lexer := scanner.Lexer
+	failedCr := lexer.cr
+	lexer.cr = begin
+	_, err := pr.parseRule(
+		debug, errCtx, newScanner(lexer), rule.ErrRule, level+1,
+	)
+	lexer.cr = failedCr
+	if err == nil {
+		return
+	}
+	switch err.(type) {
+	case *ErrUnexpectedToken, errEOF:
+		// The error-rule didn't match
+		return
+	}
+
+	if ok {
+		unexpErr.ruleErr = err
+	}
+	if ctx.ruleErrs == nil {
+		ctx.ruleErrs = map[uint]error{}
+	}
+	if _, ok := ctx.ruleErrs[begin.Index]; !ok {
+		ctx.ruleErrs[begin.Index] = err
+	}
+}
+
 func (pr Parser) tryErrRule(
 	debug *DebugProfile,
+	ctx *Context,
 	lex *lexer,
 	errRule *Rule,
 	previousUnexpErr error,
 ) error {
 	if errRule != nil {
-		_, err := pr.parseRule(debug, newScanner(lex), errRule, 0)
+		_, err := pr.parseRule(debug, ctx, newScanner(lex), errRule, 0)
+		if err == nil {
+			err = ctx.commit()
+		}
 		if err == nil {
 			// Return the previous error when no error was returned
 			return previousUnexpErr
@@ -360,11 +718,85 @@ func (pr Parser) tryErrRule(
 	return nil
 }
 
+// ParseOptions defines the options of a single parse
+type ParseOptions struct {
+	// Value defines the user value passed to actions and predicates
+	// through Context.Value
+	Value interface{}
+
+	// NoTree disables building the parse-tree when only the semantic value
+	// is needed. Rules are then represented by tokens without elements
+	// and no constructs are allocated
+	NoTree bool
+
+	// Lossless guarantees that every rune of the source file is covered by
+	// exactly one token of the parse-tree in document order.
+	// Hidden fragments are therefore replaced by their tokens
+	// instead of being removed (see VerifyLossless and Reprint)
+	Lossless bool
+
+	// NormalizeLineEndings parses a copy of the source file with all
+	// line-breaks recognized by the parser replaced by "\n".
+	// The original line-breaks are recorded in the LineBreaks
+	// of the copy (see NormalizeLineEndings)
+	NormalizeLineEndings bool
+
+	// Tolerant recovers from syntax errors always producing a parse-tree
+	// covering the entire source file. Unparsable source code is covered
+	// by tokens of KindError and expected but absent fragments are
+	// represented by empty tokens of KindMissing. Syntax errors are
+	// reported as Result.Diagnostics instead of being returned.
+	// Tolerant parses neither compute semantic values
+	// nor record matches for Reparse.
+	//
+	// Each recovery requires parsing the source file again from the start,
+	// so a source file with k syntax errors is parsed up to 2k+1 times
+	// (see MaxRecoveries)
+	Tolerant bool
+
+	// MaxRecoveries limits the number of positions a tolerant parse
+	// recovers at bounding the number of times the source file is parsed.
+	// Once it's reached, the rest of the source file following the
+	// parsed fragments is covered by a token of KindError.
+	// DefaultMaxRecoveries is used when it's 0
+	MaxRecoveries uint
+
+	// RepairTokens enables single-token repairs in tolerant parses.
+	// Before skipping source code the parser tries inserting an expected
+	// exact terminal and removing the unexpected token, accepting the first
+	// repair after which the parse advances by RepairTokens terminals or
+	// reaches the end of the source file. An expected element is insertable
+	// if it's an exact terminal, a choice of such or a rule matching such;
+	// of several insertable terminals the first one is inserted. Inserted
+	// terminals are represented by empty tokens of their kind wrapped in
+	// empty constructs of the enclosing rules and removed tokens by tokens
+	// of KindError. Repairs are disabled when RepairTokens is 0
+	RepairTokens uint
+}
+
+// Result represents the result of a parse
+type Result struct {
+	// Fragment is the main fragment of the parse-tree
+	Fragment Fragment
+
+	// Value is the semantic value computed by the reducer of the main rule
+	// and is nil if the main rule has no reducer
+	Value interface{}
+
+	// Diagnostics holds the diagnostics that didn't abort the parse
+	// in the order they were reported in
+	Diagnostics []Diagnostic
+}
+
 // Debug parses the given source file in debug mode generating a debug profile
 func (pr *Parser) Debug(source *SourceFile) (*DebugProfile, Fragment, error) {
 	debug := newDebugProfile()
-	mainFrag, err := pr.parse(source, debug)
-	return debug, mainFrag, err
+	debug.Kinds = pr.Kinds.or()
+	result, err := pr.parse(source, debug, ParseOptions{}, nil)
+	if err != nil {
+		return debug, nil, err
+	}
+	return debug, result.Fragment, nil
 }
 
 // Parse parses the given source file.
@@ -372,27 +804,82 @@ func (pr *Parser) Debug(source *SourceFile) (*DebugProfile, Fragment, error) {
 // WARNING: Parse isn't safe for concurrent use and shall therefore
 // not be executed by multiple goroutines concurrently!
 func (pr *Parser) Parse(source *SourceFile) (Fragment, error) {
-	return pr.parse(source, nil)
+	result, err := pr.parse(source, nil, ParseOptions{}, nil)
+	if err != nil {
+		return nil, err
+	}
+	return result.Fragment, nil
+}
+
+// ParseWith parses the given source file using the given options.
+//
+// WARNING: ParseWith isn't safe for concurrent use and shall therefore
+// not be executed by multiple goroutines concurrently!
+func (pr *Parser) ParseWith(
+	source *SourceFile,
+	options ParseOptions,
+) (*Result, error) {
+	return pr.parse(source, nil, options, nil)
 }
 
 func (pr *Parser) parse(
 	source *SourceFile,
 	debug *DebugProfile,
-) (Fragment, error) {
+	options ParseOptions,
+	mm *memo,
+) (result *Result, err error) {
+	defer func() {
+		switch err := err.(type) {
+		case *ErrUnexpectedToken:
+			err.kinds = pr.Kinds.or()
+			err.Suggestions = suggest(err)
+			err.verbose = pr.ErrorStacks
+		case *Err:
+			err.kinds = pr.Kinds.or()
+			err.verbose = pr.ErrorStacks
+		}
+	}()
 	if pr.MaxRecursionLevel > 0 {
 		// Reset the recursion register when recursion limitation is enabled
 		pr.recursionRegister.Reset()
 	}
+	if options.NormalizeLineEndings {
+		source = NormalizeLineEndings(source, pr.LineEndings)
+	} else {
+		source = withLineEndings(source, pr.LineEndings)
+	}
+	if options.Tolerant {
+		return pr.parseTolerant(source, debug, options)
+	}
 	cr := NewCursor(source)
 	lex := &lexer{cr: cr}
-
-	mainFrag, err := pr.parseRule(debug, newScanner(lex), pr.grammar, 0)
+	ctx := newContext(options.Value, pr.DeferActions)
+
+	scan := newScanner(lex)
+	scan.TrackValues = pr.reducing
+	scan.NoTree = options.NoTree
+	scan.Lossless = options.Lossless
+	if mm == nil && pr.Incremental {
+		mm = newMemo(source, options, pr.memoConfig())
+	}
+	scan.Memo = mm
+	mainFrag, err := pr.parseRule(debug, ctx, scan, pr.grammar, 0)
 	if err != nil {
+		ctx.attachStack(err)
+
+		// Discard all side effects of the failed parse
+		ctx.rewind(mark{})
+
 		if err, ok := err.(*ErrUnexpectedToken); ok {
+			if ruleErr := ctx.ruleErr(err); ruleErr != nil {
+				return nil, ruleErr
+			}
 			// Reset the lexer to the start position of the error
 			lex.cr = err.At
 		}
-		if err := pr.tryErrRule(debug, lex, pr.errGrammar, err); err != nil {
+		if err := pr.tryErrRule(
+			debug, ctx, lex, pr.errGrammar, err,
+		); err != nil {
 			return nil, err
 		}
 		return nil, err
@@ -406,7 +893,10 @@ func (pr *Parser) parse(
 	switch err := err.(type) {
 	case errEOF:
 		// Ignore EOF errors
-		return mainFrag, nil
+		if err := ctx.commit(); err != nil {
+			return nil, err
+		}
+		return pr.newResult(mainFrag, scan, ctx), nil
 	case nil:
 	default:
 		// Report unexpected errors
@@ -419,8 +909,16 @@ func (pr *Parser) parse(
 		}
 
 		unexpErr := &ErrUnexpectedToken{At: last.VBegin}
+		if ruleErr := ctx.ruleErr(unexpErr); ruleErr != nil {
+			return nil, ruleErr
+		}
+
+		// Discard all side effects of the failed parse
+		ctx.rewind(mark{})
 
-		if err := pr.tryErrRule(debug, lex, pr.errGrammar, unexpErr); err != nil {
+		if err := pr.tryErrRule(
+			debug, ctx, lex, pr.errGrammar, unexpErr,
+		); err != nil {
 			return nil, err
 		}
 
@@ -428,5 +926,25 @@ func (pr *Parser) parse(
 		return nil, unexpErr
 	}
 
-	return mainFrag, nil
+	if err := ctx.commit(); err != nil {
+		return nil, err
+	}
+	return pr.newResult(mainFrag, scan, ctx), nil
+}
+
+// newResult creates the result of a successful parse
+func (pr *Parser) newResult(
+	mainFrag Fragment,
+	scan *scanner,
+	ctx *Context,
+) *Result {
+	result := &Result{Fragment: mainFrag, Diagnostics: ctx.diagnostics}
+	if scan.Memo != nil {
+		scan.Memo.root = mainFrag
+		pr.memo = scan.Memo
+	}
+	if pr.grammar.Reduce != nil {
+		result.Value = scan.Result[0].Value
+	}
+	return result
 }
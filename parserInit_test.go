@@ -118,6 +118,57 @@ func TestExactMissingExpectation(t *testing.T) {
 	))
 }
 
+func TestPredicateMissingFn(t *testing.T) {
+	pd := &llp.Predicate{}
+	test(t, pd, str(
+		"invalid grammar: predicate %p is missing the predicate function",
+		pd,
+	))
+}
+
+func TestLabelMissingName(t *testing.T) {
+	test(
+		t,
+		llp.Label{Pattern: &llp.Exact{Expectation: []rune("test")}},
+		"invalid grammar: label is missing a name",
+	)
+}
+
+func TestLabelMissingPattern(t *testing.T) {
+	test(
+		t,
+		llp.Label{Name: "test"},
+		`invalid grammar: label "test" is missing a pattern`,
+	)
+}
+
+func TestShapedMissingPattern(t *testing.T) {
+	test(
+		t,
+		llp.Shaped{Shape: llp.ShapeHidden},
+		"invalid grammar: shaped pattern is missing a pattern",
+	)
+}
+
+func TestShapedInvalidShape(t *testing.T) {
+	test(
+		t,
+		llp.Shaped{
+			Shape:   llp.Shape(42),
+			Pattern: &llp.Exact{Expectation: []rune("test")},
+		},
+		"invalid grammar: shaped pattern has an invalid shape (42)",
+	)
+}
+
+func TestRuleInvalidShape(t *testing.T) {
+	rl := &llp.Rule{
+		Shape:   llp.Shape(42),
+		Pattern: &llp.Exact{Expectation: []rune("test")},
+	}
+	test(t, rl, str("invalid grammar: rule %p has an invalid shape (42)", rl))
+}
+
 func TestNotNested(t *testing.T) {
 	ex := &llp.Exact{Expectation: []rune("test")}
 	test(
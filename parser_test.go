@@ -1,9 +1,12 @@
 package parser_test
 
 import (
+	"bytes"
 	"errors"
 	"fmt"
+	"strconv"
 	"testing"
+	"unicode"
 
 	llp "github.com/romshark/llparser"
 	"github.com/stretchr/testify/require"
@@ -20,6 +23,30 @@ const (
 	FrBar
 )
 
+const (
+	kindServer llp.FragmentKind = 200 + iota
+	kindNumber
+	kindSum
+	kindVariable
+)
+
+const (
+	kindList llp.FragmentKind = 300 + iota
+	kindGroup
+)
+
+const (
+	kindFunction llp.FragmentKind = 400 + iota
+	kindBody
+	kindLambda
+)
+
+const (
+	kindPair llp.FragmentKind = 500 + iota
+	kindCall
+	kindFile
+)
+
 // Basic terminal types
 var (
 	termSpace = &llp.Lexed{
@@ -66,6 +93,15 @@ var (
 		Pattern:     &llp.Exact{Expectation: []rune("bar")},
 		Kind:        FrBar,
 	}
+	termNumber = &llp.Lexed{
+		Designation: "number",
+		Kind:        kindNumber,
+		MinLen:      1,
+		Fn: func(_ uint, crs llp.Cursor) bool {
+			rn := crs.File.Src[crs.Index]
+			return rn >= '0' && rn <= '9'
+		},
+	}
 )
 
 func newSource(src string) *llp.SourceFile {
@@ -113,12 +149,463 @@ func checkFrag(
 	require.Len(t, frag.Elements(), expectedElementsNum)
 }
 
+// requireSameTree requires both trees to be identical
+// except for the source files they refer to
+func requireSameTree(t *testing.T, expected, actual llp.Fragment) {
+	require.Equal(t, expected.Kind(), actual.Kind())
+	for _, cr := range [][2]llp.Cursor{
+		{expected.Begin(), actual.Begin()},
+		{expected.End(), actual.End()},
+	} {
+		require.Equal(t, cr[0].Index, cr[1].Index)
+		require.Equal(t, cr[0].Line, cr[1].Line)
+		require.Equal(t, cr[0].Column, cr[1].Column)
+	}
+	require.Equal(t, string(expected.Src()), string(actual.Src()))
+	require.Len(t, actual.Elements(), len(expected.Elements()))
+	for ix, el := range expected.Elements() {
+		requireSameTree(t, el, actual.Elements()[ix])
+	}
+}
+
 func newParser(t *testing.T, grammar, errGrammar *llp.Rule) *llp.Parser {
 	pr, err := llp.NewParser(grammar, errGrammar)
 	require.NoError(t, err)
 	return pr
 }
 
+// newKinds creates a registry of the names of the fragment kinds
+// of the test grammars
+func newKinds(t *testing.T) *llp.KindRegistry {
+	kinds := &llp.KindRegistry{}
+	for kind, name := range map[llp.FragmentKind]string{
+		FrSpace:      "space",
+		FrSeparator:  "separator",
+		FrWord:       "word",
+		FrFoo:        "foo",
+		FrBar:        "bar",
+		kindServer:   "server",
+		kindNumber:   "number",
+		kindSum:      "sum",
+		kindVariable: "variable",
+		kindList:     "list",
+		kindGroup:    "group",
+		kindFunction: "function",
+		kindBody:     "body",
+		kindLambda:   "lambda",
+		kindPair:     "pair",
+		kindCall:     "call",
+		kindFile:     "file",
+	} {
+		require.NoError(t, kinds.Register(kind, name))
+	}
+	return kinds
+}
+
+// mustParse parses the given source file requiring no error
+func mustParse(
+	t *testing.T,
+	pr *llp.Parser,
+	src *llp.SourceFile,
+) llp.Fragment {
+	mainFrag, err := pr.Parse(src)
+	require.NoError(t, err)
+	return mainFrag
+}
+
+// newServerGrammar returns the grammar of a server declaration such as
+// "name:8080:true[a,b]" where the debug flag and the tags are optional
+func newServerGrammar() *llp.Rule {
+	colon := &llp.Exact{Expectation: []rune(":")}
+	return &llp.Rule{
+		Designation: "server",
+		Kind:        kindServer,
+		Pattern: llp.Sequence{
+			llp.Label{Name: "name", Pattern: termLatinWord},
+			colon,
+			llp.Label{Name: "port", Pattern: termNumber},
+			&llp.Repeated{Max: 1, Pattern: llp.Sequence{
+				colon,
+				llp.Label{Name: "debug", Pattern: termLatinWord},
+			}},
+			&llp.Repeated{Max: 1, Pattern: llp.Sequence{
+				&llp.Exact{Expectation: []rune("[")},
+				llp.Label{Name: "tag", Pattern: termLatinWord},
+				&llp.Repeated{Pattern: llp.Sequence{
+					termSeparator,
+					llp.Label{Name: "tag", Pattern: termLatinWord},
+				}},
+				&llp.Exact{Expectation: []rune("]")},
+			}},
+		},
+	}
+}
+
+// newListGrammar returns the grammar of a comma-separated list of words
+// and parenthesized groups such as "a,(b,c),d"
+func newListGrammar() *llp.Rule {
+	group := &llp.Rule{Designation: "group", Kind: kindGroup}
+	item := llp.Either{termLatinWord, group}
+	list := llp.Sequence{
+		item,
+		&llp.Repeated{Pattern: llp.Sequence{termSeparator, item}},
+	}
+	group.Pattern = llp.Sequence{
+		&llp.Exact{Expectation: []rune("(")},
+		list,
+		&llp.Exact{Expectation: []rune(")")},
+	}
+	return &llp.Rule{
+		Designation: "list",
+		Kind:        kindList,
+		Pattern:     list,
+	}
+}
+
+// fragSrc returns the source codes of the given fragments
+func fragSrc(frags []llp.Fragment) []string {
+	src := make([]string, len(frags))
+	for ix, frag := range frags {
+		src[ix] = string(frag.Src())
+	}
+	return src
+}
+
+// newFunctionGrammar returns the grammar of function declarations such as
+// "fn f{a \{b} c}" where function bodies may contain nested lambdas
+func newFunctionGrammar() *llp.Rule {
+	space := llp.Shaped{Shape: llp.ShapeHidden, Pattern: termSpace}
+	body := &llp.Rule{Designation: "body", Kind: kindBody}
+	lambda := &llp.Rule{
+		Designation: "lambda",
+		Kind:        kindLambda,
+		Pattern: llp.Sequence{
+			&llp.Exact{Expectation: []rune("\\{")},
+			body,
+			&llp.Exact{Expectation: []rune("}")},
+		},
+	}
+	body.Pattern = &llp.Repeated{
+		Pattern: llp.Either{lambda, termLatinWord, space},
+	}
+	function := &llp.Rule{
+		Designation: "function",
+		Kind:        kindFunction,
+		Pattern: llp.Sequence{
+			&llp.Exact{Expectation: []rune("fn")},
+			space,
+			termLatinWord,
+			&llp.Exact{Expectation: []rune("{")},
+			body,
+			&llp.Exact{Expectation: []rune("}")},
+		},
+	}
+	return &llp.Rule{
+		Designation: "program",
+		Pattern: &llp.Repeated{
+			Pattern: llp.Either{function, space},
+		},
+	}
+}
+
+// queryMatches returns the matches of the given query in root
+func queryMatches(
+	t *testing.T,
+	query string,
+	root llp.Fragment,
+) []llp.QueryMatch {
+	q, err := llp.CompileQuery(query, newKinds(t))
+	require.NoError(t, err)
+	require.Equal(t, query, q.String())
+	return q.Matches(root)
+}
+
+// readWord reads the latin word at the given cursor
+func readWord(crs llp.Cursor) string {
+	end := crs.Index
+	for ; end < uint(len(crs.File.Src)); end++ {
+		rn := crs.File.Src[end]
+		if !(rn >= 'a' && rn <= 'z' || rn >= 'A' && rn <= 'Z') {
+			break
+		}
+	}
+	return string(crs.File.Src[crs.Index:end])
+}
+
+// newSymbolGrammar returns a grammar of a simple language consisting of
+// declarations ("$name;" or "$name!"), usages ("name;")
+// and scoped blocks ("{...}")
+func newSymbolGrammar() *llp.Rule {
+	ident := &llp.Lexed{
+		Designation: "identifier",
+		Kind:        FrWord,
+		MinLen:      1,
+		Fn: func(_ uint, crs llp.Cursor) bool {
+			rn := crs.File.Src[crs.Index]
+			return rn >= 'a' && rn <= 'z' || rn >= 'A' && rn <= 'Z'
+		},
+	}
+	decl := &llp.Rule{
+		Designation: "declaration",
+		Pattern:     llp.Sequence{&llp.Exact{Expectation: []rune("$")}, ident},
+		Action: func(ctx *llp.Context, f llp.Fragment) error {
+			name := string(f.Elements()[1].Src())
+			if _, ok := ctx.LookupLocal(name); ok {
+				return fmt.Errorf("%q redeclared", name)
+			}
+			ctx.Declare(name, f)
+			return nil
+		},
+	}
+	use := &llp.Rule{
+		Designation: "usage",
+		Pattern: llp.Sequence{
+			&llp.Predicate{
+				Designation: "declared identifier",
+				Fn: func(ctx *llp.Context, crs llp.Cursor) bool {
+					_, ok := ctx.Lookup(readWord(crs))
+					return ok
+				},
+			},
+			ident,
+		},
+	}
+	stmt := &llp.Rule{Designation: "statement"}
+	block := &llp.Rule{
+		Designation: "block",
+		Scoped:      true,
+		Pattern: llp.Sequence{
+			&llp.Exact{Expectation: []rune("{")},
+			&llp.Repeated{Pattern: stmt},
+			&llp.Exact{Expectation: []rune("}")},
+		},
+	}
+	stmt.Pattern = llp.Either{
+		block,
+		llp.Sequence{decl, &llp.Exact{Expectation: []rune(";")}},
+		llp.Sequence{decl, &llp.Exact{Expectation: []rune("!")}},
+		llp.Sequence{use, &llp.Exact{Expectation: []rune(";")}},
+	}
+	return &llp.Rule{
+		Designation: "file",
+		Pattern:     &llp.Repeated{Min: 1, Pattern: stmt},
+	}
+}
+
+// newCutGrammar returns the grammar of comma-terminated function
+// declarations and expressions committing to function declarations
+// after the "func" keyword if cut is true
+func newCutGrammar(cut bool) *llp.Rule {
+	funcSeq := llp.Sequence{
+		&llp.Exact{Expectation: []rune("func")},
+		termSpace,
+		termLatinWord,
+		&llp.Exact{Expectation: []rune("()")},
+	}
+	if cut {
+		funcSeq = llp.Sequence{
+			funcSeq[0],
+			llp.Cut{},
+			funcSeq[1],
+			funcSeq[2],
+			funcSeq[3],
+		}
+	}
+	return &llp.Rule{
+		Designation: "statements",
+		Pattern: &llp.Repeated{
+			Pattern: llp.Sequence{
+				llp.Either{
+					&llp.Rule{
+						Designation: "function declaration",
+						Pattern:     funcSeq,
+					},
+					&llp.Rule{
+						Designation: "expression",
+						Pattern:     termLatinWord,
+					},
+				},
+				termSeparator,
+			},
+		},
+	}
+}
+
+// newCalcGrammar returns the grammar of a calculator supporting
+// additions, multiplications and parentheses reducing to the result
+func newCalcGrammar() *llp.Rule {
+	hidden := func(expectation string) llp.Shaped {
+		return llp.Shaped{
+			Shape:   llp.ShapeHidden,
+			Pattern: &llp.Exact{Expectation: []rune(expectation)},
+		}
+	}
+	// fold folds integer values using the given operator
+	fold := func(fn func(a, b int) int) llp.Reducer {
+		return func(
+			_ *llp.Context,
+			_ llp.Fragment,
+			values []interface{},
+		) (interface{}, error) {
+			result := values[0].(int)
+			for _, val := range values[1:] {
+				result = fn(result, val.(int))
+			}
+			return result, nil
+		}
+	}
+
+	sum := &llp.Rule{
+		Designation: "sum",
+		Reduce:      fold(func(a, b int) int { return a + b }),
+	}
+	number := &llp.Rule{
+		Designation: "number",
+		Pattern:     termNumber,
+		Reduce: func(
+			_ *llp.Context,
+			_ llp.Fragment,
+			values []interface{},
+		) (interface{}, error) {
+			return strconv.Atoi(string(values[0].(*llp.Token).Src()))
+		},
+	}
+	factor := &llp.Rule{
+		Designation: "factor",
+		Pattern: llp.Either{
+			number,
+			llp.Sequence{hidden("("), sum, hidden(")")},
+		},
+	}
+	product := &llp.Rule{
+		Designation: "product",
+		Reduce:      fold(func(a, b int) int { return a * b }),
+		Pattern: llp.Sequence{
+			factor,
+			&llp.Repeated{Pattern: llp.Sequence{hidden("*"), factor}},
+		},
+	}
+	sum.Pattern = llp.Sequence{
+		product,
+		&llp.Repeated{Pattern: llp.Sequence{hidden("+"), product}},
+	}
+	return sum
+}
+
+// newPairGrammar creates an undesignated grammar of
+// two words separated by a comma
+func newPairGrammar() *llp.Rule {
+	word := &llp.Lexed{
+		Kind:   FrWord,
+		MinLen: 1,
+		Fn: func(_ uint, cr llp.Cursor) bool {
+			return unicode.IsLetter(cr.File.Src[cr.Index])
+		},
+	}
+	return &llp.Rule{
+		Kind: kindPair,
+		Pattern: llp.Sequence{
+			word,
+			&llp.Exact{Kind: FrSeparator, Expectation: []rune(",")},
+			word,
+		},
+	}
+}
+
+// newItemRule creates a rule matching either foo or bar
+// with an error-rule reporting unknown words
+func newItemRule() *llp.Rule {
+	return &llp.Rule{
+		Designation: "item",
+		Pattern:     llp.Either{testR_foo, testR_bar},
+		ErrRule: &llp.Rule{
+			Pattern: termLatinWord,
+			Action: func(_ *llp.Context, fr llp.Fragment) error {
+				return fmt.Errorf("unknown item %q", string(fr.Src()))
+			},
+		},
+	}
+}
+
+// newCallGrammar creates a grammar of call statements like "f(x);"
+func newCallGrammar() *llp.Rule {
+	word := &llp.Lexed{
+		Designation: "word",
+		Kind:        FrWord,
+		MinLen:      1,
+		Fn: func(_ uint, cr llp.Cursor) bool {
+			return unicode.IsLetter(cr.File.Src[cr.Index])
+		},
+	}
+	punct := func(str string) *llp.Exact {
+		return &llp.Exact{Kind: FrSeparator, Expectation: []rune(str)}
+	}
+	call := &llp.Rule{
+		Designation: "call",
+		Kind:        kindCall,
+		Pattern: llp.Sequence{
+			word,
+			punct("("),
+			&llp.Repeated{Max: 1, Pattern: word},
+			punct(")"),
+			punct(";"),
+		},
+	}
+	return &llp.Rule{
+		Designation: "file",
+		Kind:        kindFile,
+		Pattern:     &llp.Repeated{Pattern: call},
+	}
+}
+
+// parseTolerant parses the source code in tolerant mode returning the
+// parse-tree as an S-expression, the summarized diagnostics and
+// the source code with all fixes applied
+func parseTolerant(
+	t *testing.T,
+	pr *llp.Parser,
+	src string,
+	options llp.ParseOptions,
+) (string, []string, string) {
+	options.Tolerant = true
+	options.Lossless = true
+	result, err := pr.ParseWith(newSource(src), options)
+	require.NoError(t, err)
+	require.NotNil(t, result)
+	require.NoError(t, llp.VerifyLossless(result.Fragment))
+	require.Equal(t, uint(len([]rune(src))), result.Fragment.End().Index)
+
+	tree := &bytes.Buffer{}
+	_, err = llp.PrintFragment(result.Fragment, llp.FragPrintOptions{
+		Out:     tree,
+		Backend: llp.PrintSExpr,
+		Kinds:   pr.Kinds,
+	})
+	require.NoError(t, err)
+
+	var diags []string
+	var edits []llp.Edit
+	for _, diag := range result.Diagnostics {
+		require.Equal(t, llp.SeverityError, diag.Severity)
+		diags = append(diags, fmt.Sprintf(
+			"%s %d-%d: %s",
+			diag.Code, diag.Begin.Index, diag.End.Index, diag.Message,
+		))
+		for _, fix := range diag.Fixes {
+			edits = append(edits, fix.Edits...)
+		}
+	}
+	fixed := []rune(src)
+	for ix := len(edits) - 1; ix >= 0; ix-- {
+		edit := edits[ix]
+		fixed = append(
+			fixed[:edit.Begin:edit.Begin],
+			append([]rune(edit.Text), fixed[edit.End:]...)...,
+		)
+	}
+	return tree.String(), diags, string(fixed)
+}
+
 func TestParserSequence(t *testing.T) {
 	t.Run("SingleLevel", func(t *testing.T) {
 		expectedKind := llp.FragmentKind(100)
@@ -663,7 +1150,8 @@ func TestParserEither(t *testing.T) {
 		require.Equal(
 			t,
 			"unexpected token, expected {either of "+
-				"[keyword foo, keyword bar]} at test.txt:1:1",
+				"[keyword foo, keyword bar]} at test.txt:1:1, "+
+				"did you mean 'bar'?",
 			err.Error(),
 		)
 		require.Nil(t, mainFrag)
@@ -761,7 +1249,7 @@ func TestParserAction(t *testing.T) {
 		Designation: "a",
 		Kind:        aKind,
 		Pattern:     &llp.Exact{FrWord, []rune("a")},
-		Action: func(f llp.Fragment) error {
+		Action: func(_ *llp.Context, f llp.Fragment) error {
 			aFrags = append(aFrags, f)
 			return nil
 		},
@@ -771,7 +1259,7 @@ func TestParserAction(t *testing.T) {
 		Designation: "b",
 		Kind:        bKind,
 		Pattern:     &llp.Exact{FrWord, []rune("b")},
-		Action: func(f llp.Fragment) error {
+		Action: func(_ *llp.Context, f llp.Fragment) error {
 			bFrags = append(bFrags, f)
 			return nil
 		},
@@ -811,7 +1299,7 @@ func TestParserActionErr(t *testing.T) {
 		Designation: "a",
 		Kind:        900,
 		Pattern:     &llp.Exact{FrWord, []rune("a")},
-		Action: func(f llp.Fragment) error {
+		Action: func(_ *llp.Context, f llp.Fragment) error {
 			return expectedErr
 		},
 	}, nil)
@@ -925,7 +1413,7 @@ func TestParserErrRule(t *testing.T) {
 					Pattern: &llp.Exact{Expectation: []rune(".")},
 				},
 			},
-			Action: func(fr llp.Fragment) error {
+			Action: func(_ *llp.Context, fr llp.Fragment) error {
 				return fmt.Errorf("expected 3 dots, got %d", len(fr.Src()))
 			},
 		}
@@ -954,7 +1442,7 @@ func TestParserErrRule(t *testing.T) {
 				Min:     1,
 				Pattern: &llp.Exact{Expectation: []rune(";")},
 			},
-			Action: func(fr llp.Fragment) error {
+			Action: func(_ *llp.Context, fr llp.Fragment) error {
 				return fmt.Errorf(
 					"expected 3 semicolons, got %d",
 					len(fr.Src()),
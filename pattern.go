@@ -41,6 +41,12 @@ type Lexed struct {
 	Designation string
 	MinLen      uint
 	Fn          func(index uint, cursor Cursor) bool
+
+	// Lookahead is the number of runes following the one at the cursor
+	// that Fn may examine. Fn must not examine any other runes, neither
+	// preceding the beginning of the token nor beyond the lookahead,
+	// otherwise Reparse may reuse matches that depend on edited source code
+	Lookahead uint
 }
 
 // Container implements the Pattern interface
@@ -150,3 +156,71 @@ func (not Not) TerminalPattern() Pattern { return not.Pattern }
 func (not Not) Desig() string {
 	return "not a " + not.Pattern.Desig()
 }
+
+// Label represents a named capture of the fragments matched by a pattern.
+// Labeled fragments can be accessed by name through Construct.Child and
+// Construct.Children. When labels are nested the innermost label is kept
+type Label struct {
+	Name    string
+	Pattern Pattern
+}
+
+// Container implements the Pattern interface
+func (Label) Container() bool { return true }
+
+// TerminalPattern implements the Pattern interface
+func (lb Label) TerminalPattern() Pattern { return lb.Pattern }
+
+// Desig implements the Pattern interface
+func (lb Label) Desig() string { return lb.Pattern.Desig() }
+
+// Predicate represents a semantic predicate that doesn't consume any input
+// and matches only if Fn returns true
+type Predicate struct {
+	Designation string
+	Fn          func(ctx *Context, cursor Cursor) bool
+}
+
+// Container implements the Pattern interface
+func (*Predicate) Container() bool { return false }
+
+// TerminalPattern implements the Pattern interface
+func (*Predicate) TerminalPattern() Pattern { return nil }
+
+// Desig implements the Pattern interface
+func (pd *Predicate) Desig() string { return pd.Designation }
+
+// Cut represents a commitment to the current alternative.
+// Once a cut is passed a failure is never backtracked from: neither the
+// enclosing Either nor any other Either or Repeated enclosing it, even
+// across rules, tries further options or stops repeating. Instead, the parse
+// fails at the position the failure actually occurred at. Only a Not
+// confines the cuts of its pattern. Reparse reuses the matches of rules
+// containing cuts and commits the enclosing choice point again if the
+// original match did
+type Cut struct{}
+
+// Container implements the Pattern interface
+func (Cut) Container() bool { return true }
+
+// TerminalPattern implements the Pattern interface
+func (Cut) TerminalPattern() Pattern { return nil }
+
+// Desig implements the Pattern interface
+func (Cut) Desig() string { return "cut" }
+
+// Shaped represents a pattern whose fragments are shaped before they're
+// added to the parse-tree (see Shape)
+type Shaped struct {
+	Shape   Shape
+	Pattern Pattern
+}
+
+// Container implements the Pattern interface
+func (Shaped) Container() bool { return true }
+
+// TerminalPattern implements the Pattern interface
+func (sh Shaped) TerminalPattern() Pattern { return sh.Pattern }
+
+// Desig implements the Pattern interface
+func (sh Shaped) Desig() string { return sh.Pattern.Desig() }
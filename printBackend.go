@@ -0,0 +1,264 @@
+package parser
+
+import (
+	"fmt"
+	"html"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// PrintBackend defines the output format of PrintFragment
+type PrintBackend int
+
+const (
+	// PrintIndented prints an indented tree of stringified fragments
+	PrintIndented PrintBackend = iota
+
+	// PrintDOT prints a Graphviz DOT graph
+	PrintDOT
+
+	// PrintSExpr prints an S-expression
+	PrintSExpr
+
+	// PrintHTML prints a self-contained HTML page of collapsible fragments
+	// highlighting the source code of a fragment when hovered
+	PrintHTML
+)
+
+// printer writes to the output of a backend counting the written bytes
+// and stopping at the first error
+type printer struct {
+	out     io.Writer
+	options FragPrintOptions
+	written int
+	err     error
+}
+
+func (p *printer) write(strs ...string) {
+	for _, str := range strs {
+		if p.err != nil {
+			return
+		}
+		var bw int
+		bw, p.err = io.WriteString(p.out, str)
+		p.written += bw
+	}
+}
+
+// name returns the name of the kind of the fragment
+// or its number if it has no registered name
+func (p *printer) name(frag Fragment) string {
+	return p.options.Kinds.nameOf(frag.Kind())
+}
+
+func (p *printer) elements(frag Fragment) []Fragment {
+	return shapeElements(frag.Elements(), p.options.Shape)
+}
+
+func (p *printer) lineBreak() string {
+	if p.options.LineBreak != nil {
+		return string(p.options.LineBreak)
+	}
+	return string(snipLineBreak)
+}
+
+func span(frag Fragment) string {
+	begin, end := frag.Begin(), frag.End()
+	return strconv.FormatUint(uint64(begin.Line), 10) + ":" +
+		strconv.FormatUint(uint64(begin.Column), 10) + "-" +
+		strconv.FormatUint(uint64(end.Line), 10) + ":" +
+		strconv.FormatUint(uint64(end.Column), 10)
+}
+
+// printDOT prints a digraph of numbered nodes labeled with the kind name
+// and the span of the fragment and the source code of tokens
+func (p *printer) printDOT(fragment Fragment) {
+	quote := strings.NewReplacer(
+		`\`, `\\`,
+		`"`, `\"`,
+		"\n", `\\n`,
+		"\r", `\\r`,
+		"\t", `\\t`,
+	)
+	lnBrk := p.lineBreak()
+	p.write(string(p.options.Prefix), "digraph {", lnBrk)
+
+	nodes := 0
+	var node func(frag Fragment) int
+	node = func(frag Fragment) int {
+		id := nodes
+		nodes++
+		label := p.name(frag) + `\n` + span(frag)
+		shape := "ellipse"
+		if isToken(frag) {
+			label += `\n'` + quote.Replace(string(frag.Src())) + `'`
+			shape = "box"
+		}
+		p.write(
+			string(p.options.Prefix), string(p.options.Indentation),
+			"n", strconv.Itoa(id),
+			` [label="`, label, `" shape=`, shape, "];", lnBrk,
+		)
+		for _, el := range p.elements(frag) {
+			child := node(el)
+			p.write(
+				string(p.options.Prefix), string(p.options.Indentation),
+				"n", strconv.Itoa(id), " -> n", strconv.Itoa(child), ";",
+				lnBrk,
+			)
+		}
+		return id
+	}
+	node(fragment)
+	p.write(string(p.options.Prefix), "}", lnBrk)
+}
+
+// printSExpr prints fragments as lists of the kind name followed by
+// either the quoted source code of tokens or the elements of constructs.
+// Elements are printed on separate lines when an indentation is defined
+func (p *printer) printSExpr(fragment Fragment) {
+	var expr func(ind int, frag Fragment)
+	expr = func(ind int, frag Fragment) {
+		p.write("(", p.name(frag))
+		if isToken(frag) {
+			p.write(" ", strconv.Quote(string(frag.Src())), ")")
+			return
+		}
+		for _, el := range p.elements(frag) {
+			if len(p.options.Indentation) < 1 {
+				p.write(" ")
+			} else {
+				p.write(p.lineBreak(), string(p.options.Prefix))
+				for ix := 0; ix <= ind; ix++ {
+					p.write(string(p.options.Indentation))
+				}
+			}
+			expr(ind+1, el)
+		}
+		p.write(")")
+	}
+	p.write(string(p.options.Prefix))
+	expr(0, fragment)
+}
+
+const htmlHead = `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<style>
+body{font-family:monospace;display:flex}
+.tree,.src{flex:1;margin:1em}
+.src{white-space:pre-wrap}
+details{margin-left:1.5em}
+.tk{margin-left:1.5em}
+.hl{background:#fd6}
+.kind{font-weight:bold}
+.span{color:#888}
+</style>
+</head>
+<body>
+<div class="tree">
+`
+
+const htmlTail = `</div>
+<script>
+var segs = document.querySelectorAll(".src span");
+document.querySelectorAll(".tree [data-b]").forEach(function(node) {
+	function mark(on) {
+		var b = +node.dataset.b, e = +node.dataset.e;
+		segs.forEach(function(s) {
+			if (+s.dataset.b >= b && +s.dataset.e <= e) {
+				s.classList.toggle("hl", on);
+			}
+		});
+	}
+	node.addEventListener("mouseover", function(ev) {
+		ev.stopPropagation(); mark(true);
+	});
+	node.addEventListener("mouseout", function(ev) {
+		ev.stopPropagation(); mark(false);
+	});
+});
+</script>
+</body>
+</html>
+`
+
+// printHTML prints a page of the collapsible fragment tree next to the
+// source code of its tokens. The source code of each token is taken from
+// its own source file, since edited trees may combine several files
+func (p *printer) printHTML(fragment Fragment) {
+	p.write(htmlHead)
+
+	var tokens []Fragment
+	var node func(frag Fragment)
+	node = func(frag Fragment) {
+		begin, end := frag.Begin().Index, frag.End().Index
+		attrs := ` data-b="` + strconv.FormatUint(uint64(begin), 10) +
+			`" data-e="` + strconv.FormatUint(uint64(end), 10) + `"`
+		head := `<span class="kind">` + html.EscapeString(p.name(frag)) +
+			`</span> <span class="span">` + span(frag) + `</span>`
+		if isToken(frag) {
+			tokens = append(tokens, frag)
+			p.write(
+				`<div class="tk"`, attrs, ">", head, " ",
+				html.EscapeString(strconv.Quote(string(frag.Src()))),
+				"</div>\n",
+			)
+			return
+		}
+		p.write("<details open", attrs, "><summary>", head, "</summary>\n")
+		for _, el := range p.elements(frag) {
+			node(el)
+		}
+		p.write("</details>\n")
+	}
+	node(fragment)
+
+	// Print the source code of the tokens including the source code
+	// between subsequent tokens of the same file
+	segment := func(begin, end uint, src []rune) {
+		p.write(
+			`<span data-b="`, strconv.FormatUint(uint64(begin), 10),
+			`" data-e="`, strconv.FormatUint(uint64(end), 10), `">`,
+			html.EscapeString(string(src)), "</span>",
+		)
+	}
+	p.write("</div>\n<div class=\"src\">")
+	var prev Cursor
+	for _, tk := range tokens {
+		begin, end := tk.Begin(), tk.End()
+		file := begin.File
+		if file == nil {
+			segment(begin.Index, end.Index, tk.Src())
+			prev = end
+			continue
+		}
+		if file == prev.File && begin.Index > prev.Index {
+			segment(prev.Index, begin.Index, file.Src[prev.Index:begin.Index])
+		}
+		segment(begin.Index, end.Index, file.Src[begin.Index:end.Index])
+		prev = end
+	}
+	p.write(htmlTail)
+}
+
+// printBackend prints the fragment using the backend of the options
+func printBackend(
+	fragment Fragment,
+	options FragPrintOptions,
+) (int, error) {
+	p := &printer{out: options.Out, options: options}
+	switch options.Backend {
+	case PrintDOT:
+		p.printDOT(fragment)
+	case PrintSExpr:
+		p.printSExpr(fragment)
+	case PrintHTML:
+		p.printHTML(fragment)
+	default:
+		return 0, fmt.Errorf("unsupported print backend: %d", options.Backend)
+	}
+	return p.written, p.err
+}
@@ -19,6 +19,20 @@ type FragPrintOptions struct {
 	Prefix      []byte
 	LineBreak   []byte
 	Format      func(Fragment) (head, body []byte)
+
+	// Shape optionally defines the shape of the printed element fragments.
+	// Hidden fragments aren't printed, inlined and collapsed constructs
+	// are printed as their elements instead
+	Shape func(Fragment) Shape
+
+	// Backend defines the output format. Format is only used by
+	// the default PrintIndented backend while the other backends
+	// identify fragments by their kind names
+	Backend PrintBackend
+
+	// Kinds optionally defines the kind names used by the backends.
+	// DefaultKinds is used when nil
+	Kinds *KindRegistry
 }
 
 // PrintFragment prints the fragment structure recursively
@@ -30,6 +44,10 @@ func PrintFragment(
 		// Use stdout by default
 		options.Out = os.Stdout
 	}
+	options.Kinds = options.Kinds.or()
+	if options.Backend != PrintIndented {
+		return printBackend(fragment, options)
+	}
 
 	// write returns true if there was an error,
 	// otherwise returns false
@@ -104,7 +122,7 @@ func PrintFragment(
 			}
 			if head == nil {
 				// Fallback to the default stringification method
-				head = []byte(frag.String())
+				head = []byte(frag.Token.string(options.Kinds))
 			}
 			if write(head) {
 				return true
@@ -121,7 +139,10 @@ func PrintFragment(
 			if writeLnBrk() {
 				return true
 			}
-			for _, subFrag := range frag.VElements {
+			for _, subFrag := range shapeElements(
+				frag.VElements,
+				options.Shape,
+			) {
 				if printFrag(ind+1, subFrag) {
 					return true
 				}
@@ -142,7 +163,7 @@ func PrintFragment(
 			}
 			if head == nil {
 				// Fallback to the default stringification method
-				head = []byte(frag.String())
+				head = []byte(frag.string(options.Kinds))
 			}
 			if write(head) {
 				return true
@@ -3,6 +3,7 @@ package parser_test
 import (
 	"bytes"
 	"fmt"
+	"strings"
 	"testing"
 
 	llp "github.com/romshark/llparser"
@@ -101,4 +102,120 @@ func TestPrintFragment(t *testing.T) {
 			"100 (test.txt: 1:1-1:7 'abcdef') <2 collapsed>",
 		)
 	})
+
+	kinds := &llp.KindRegistry{}
+	require.NoError(t, kinds.Register(100, "main"))
+	require.NoError(t, kinds.Register(101, "abc"))
+
+	t.Run("DOT", func(t *testing.T) {
+		test(
+			t,
+			llp.FragPrintOptions{
+				Backend:     llp.PrintDOT,
+				Kinds:       kinds,
+				Indentation: []byte("  "),
+			},
+			"digraph {\n"+
+				"  n0 [label=\"main\\n1:1-1:7\" shape=ellipse];\n"+
+				"  n1 [label=\"abc\\n1:1-1:4\\n'abc'\" shape=box];\n"+
+				"  n0 -> n1;\n"+
+				"  n2 [label=\"102\\n1:4-1:7\\n'def'\" shape=box];\n"+
+				"  n0 -> n2;\n"+
+				"}\n",
+		)
+	})
+
+	t.Run("SExpr", func(t *testing.T) {
+		test(
+			t,
+			llp.FragPrintOptions{Backend: llp.PrintSExpr, Kinds: kinds},
+			`(main (abc "abc") (102 "def"))`,
+		)
+	})
+
+	t.Run("SExprIndented", func(t *testing.T) {
+		test(
+			t,
+			llp.FragPrintOptions{
+				Backend:     llp.PrintSExpr,
+				Kinds:       kinds,
+				Indentation: []byte("\t"),
+				Shape: func(frag llp.Fragment) llp.Shape {
+					if frag.Kind() == 102 {
+						return llp.ShapeHidden
+					}
+					return llp.ShapeKeep
+				},
+			},
+			"(main\n\t(abc \"abc\"))",
+		)
+	})
+}
+
+func TestPrintFragmentHTML(t *testing.T) {
+	pr := newParser(t, newFunctionGrammar(), nil)
+	mainFrag := mustParse(t, pr, newSource("fn f{a b}"))
+	kinds := newKinds(t)
+	bf := &bytes.Buffer{}
+	bytesWritten, err := llp.PrintFragment(mainFrag, llp.FragPrintOptions{
+		Out:     bf,
+		Backend: llp.PrintHTML,
+		Kinds:   kinds,
+	})
+	require.NoError(t, err)
+	require.Equal(t, bf.Len(), bytesWritten)
+
+	page := bf.String()
+	require.True(t, strings.HasPrefix(page, "<!DOCTYPE html>"))
+	for _, expected := range []string{
+		`<details open data-b="0" data-e="9"><summary>` +
+			`<span class="kind">0</span> <span class="span">1:1-1:10</span>`,
+		`<details open data-b="0" data-e="9"><summary>` +
+			`<span class="kind">function</span>`,
+		`<div class="tk" data-b="3" data-e="4">` +
+			`<span class="kind">word</span> ` +
+			`<span class="span">1:4-1:5</span> &#34;f&#34;</div>`,
+		`<span data-b="4" data-e="5">{</span>`,
+		`<span data-b="5" data-e="6">a</span>`,
+	} {
+		require.Contains(t, page, expected)
+	}
+}
+
+// TestPrintFragmentHTMLFiles tests printing the source code of trees
+// combining tokens of several source files
+func TestPrintFragmentHTMLFiles(t *testing.T) {
+	pr := newParser(t, newFunctionGrammar(), nil)
+	mainFrag := mustParse(t, pr, newSource("fn f{a}"))
+	bf := &bytes.Buffer{}
+	_, err := llp.PrintFragment(llp.NewConstruct(
+		kindList,
+		mainFrag,
+		llp.NewToken(FrWord, "zz"),
+	), llp.FragPrintOptions{
+		Out:     bf,
+		Backend: llp.PrintHTML,
+		Kinds:   newKinds(t),
+	})
+	require.NoError(t, err)
+
+	page := bf.String()
+	src := page[strings.Index(page, `<div class="src">`):]
+	require.True(t, strings.HasPrefix(src,
+		`<div class="src"><span data-b="0" data-e="2">fn</span>`+
+			`<span data-b="2" data-e="3"> </span>`,
+	))
+	require.Contains(t, src,
+		`<span data-b="6" data-e="7">}</span>`+
+			`<span data-b="0" data-e="2">zz</span></div>`,
+	)
+}
+
+func TestPrintFragmentUnsupportedBackend(t *testing.T) {
+	pr := newParser(t, newFunctionGrammar(), nil)
+	_, err := llp.PrintFragment(
+		mustParse(t, pr, newSource("fn f{a}")),
+		llp.FragPrintOptions{Out: &bytes.Buffer{}, Backend: 99},
+	)
+	require.EqualError(t, err, "unsupported print backend: 99")
 }
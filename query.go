@@ -0,0 +1,471 @@
+package parser
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// queryCombinator represents the relation of a query step
+// to its preceding step
+type queryCombinator int
+
+const (
+	combDescendant queryCombinator = iota
+	combChild
+)
+
+// queryStep represents a single step of a query
+type queryStep struct {
+	comb       queryCombinator
+	any        bool
+	kind       FragmentKind
+	capture    string
+	predicates []func(src string) bool
+	outside    []FragmentKind
+}
+
+// matches returns true if the fragment matches the step
+// regardless of its ancestors
+func (st *queryStep) matches(frag Fragment) bool {
+	if !st.any && frag.Kind() != st.kind {
+		return false
+	}
+	if len(st.predicates) > 0 {
+		src := string(frag.Src())
+		for _, pred := range st.predicates {
+			if !pred(src) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// excludes returns true if the fragment is of a kind the step
+// must not be nested in
+func (st *queryStep) excludes(frag Fragment) bool {
+	for _, kind := range st.outside {
+		if frag.Kind() == kind {
+			return true
+		}
+	}
+	return false
+}
+
+// Query represents a compiled structural query over fragment trees.
+//
+// A query consists of steps separated by combinators where whitespace
+// selects descendants and ">" selects direct elements of the fragment
+// matched by the preceding step:
+//
+//	function > body identifier@id
+//
+// A step selects fragments by kind name, by kind number ("#12")
+// or any fragment ("*") and can be followed by:
+//
+//	@name                 capturing the matched fragment
+//	[text="foo"]          predicates on the source text of the fragment
+//	                      supporting =, !=, ^= (prefix), $= (suffix),
+//	                      *= (contains) and ~= (regular expression)
+//	:outside(a, b)        excluding fragments nested in fragments of the
+//	                      given kinds below the fragment matched by the
+//	                      preceding step (or anywhere for the first step)
+type Query struct {
+	src   string
+	steps []queryStep
+}
+
+// QueryMatch represents a match of a query
+type QueryMatch struct {
+	// Fragment is the fragment matched by the last step of the query
+	Fragment Fragment
+
+	// Captures holds the captured fragments by name
+	Captures map[string]Fragment
+}
+
+// CompileQuery compiles the given query resolving kind names
+// using the given kind registry or DefaultKinds if it is nil
+func CompileQuery(query string, kinds *KindRegistry) (*Query, error) {
+	qp := &queryParser{src: []rune(query), kinds: kinds.or()}
+	steps, err := qp.parse()
+	if err != nil {
+		return nil, err
+	}
+	return &Query{src: query, steps: steps}, nil
+}
+
+// MustCompileQuery is like CompileQuery but panics
+// if the query can't be compiled
+func MustCompileQuery(query string, kinds *KindRegistry) *Query {
+	q, err := CompileQuery(query, kinds)
+	if err != nil {
+		panic(err)
+	}
+	return q
+}
+
+// String returns the source of the query
+func (q *Query) String() string { return q.src }
+
+// Matches returns all matches of the query in the tree of the given
+// root fragment in pre-order. A fragment is matched multiple times
+// only if its captures differ
+func (q *Query) Matches(root Fragment) []QueryMatch {
+	var matches []QueryMatch
+	var path []Fragment
+	captures := map[string]Fragment{}
+
+	walk(root, func(frag Fragment) Visit {
+		first := len(matches)
+		q.match(len(q.steps)-1, frag, path, captures, func() {
+			match := QueryMatch{Fragment: frag}
+			if len(captures) > 0 {
+				match.Captures = make(map[string]Fragment, len(captures))
+				for name, captured := range captures {
+					match.Captures[name] = captured
+				}
+			}
+			for _, previous := range matches[first:] {
+				if sameCaptures(previous.Captures, match.Captures) {
+					return
+				}
+			}
+			matches = append(matches, match)
+		})
+		path = append(path, frag)
+		return VisitContinue
+	}, func(Fragment) Visit {
+		path = path[:len(path)-1]
+		return VisitContinue
+	})
+	return matches
+}
+
+// match tries to match the given fragment against the step at the given
+// index and all its preceding steps against the ancestors of the fragment
+// calling emit for every successful match
+func (q *Query) match(
+	index int,
+	frag Fragment,
+	ancestors []Fragment,
+	captures map[string]Fragment,
+	emit func(),
+) {
+	st := &q.steps[index]
+	if !st.matches(frag) {
+		return
+	}
+	if st.capture != "" {
+		// Restore the binding of a reused capture name afterwards
+		previous, bound := captures[st.capture]
+		captures[st.capture] = frag
+		defer func() {
+			if bound {
+				captures[st.capture] = previous
+			} else {
+				delete(captures, st.capture)
+			}
+		}()
+	}
+
+	if index == 0 {
+		for _, anc := range ancestors {
+			if st.excludes(anc) {
+				return
+			}
+		}
+		emit()
+		return
+	}
+
+	switch st.comb {
+	case combChild:
+		if len(ancestors) < 1 {
+			return
+		}
+		parent := len(ancestors) - 1
+		q.match(index-1, ancestors[parent], ancestors[:parent], captures, emit)
+	case combDescendant:
+		for ix := len(ancestors) - 1; ix >= 0; ix-- {
+			q.match(index-1, ancestors[ix], ancestors[:ix], captures, emit)
+			if st.excludes(ancestors[ix]) {
+				// Ancestors further up would have this one in between
+				return
+			}
+		}
+	}
+}
+
+func sameCaptures(a, b map[string]Fragment) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for name, frag := range a {
+		if b[name] != frag {
+			return false
+		}
+	}
+	return true
+}
+
+// queryParser represents a query compiler
+type queryParser struct {
+	src   []rune
+	pos   int
+	kinds *KindRegistry
+}
+
+func (qp *queryParser) errf(format string, a ...interface{}) error {
+	return fmt.Errorf(
+		"invalid query at offset %d: %s",
+		qp.pos,
+		fmt.Sprintf(format, a...),
+	)
+}
+
+func (qp *queryParser) eof() bool { return qp.pos >= len(qp.src) }
+
+func (qp *queryParser) peek() rune {
+	if qp.eof() {
+		return 0
+	}
+	return qp.src[qp.pos]
+}
+
+// skipSpace skips whitespace returning true if any was skipped
+func (qp *queryParser) skipSpace() bool {
+	begin := qp.pos
+	for !qp.eof() && unicode.IsSpace(qp.peek()) {
+		qp.pos++
+	}
+	return qp.pos > begin
+}
+
+func isQueryIdentRune(rn rune) bool {
+	return unicode.IsLetter(rn) || unicode.IsDigit(rn) ||
+		rn == '_' || rn == '-' || rn == '.'
+}
+
+func (qp *queryParser) ident() string {
+	begin := qp.pos
+	for !qp.eof() && isQueryIdentRune(qp.peek()) {
+		qp.pos++
+	}
+	return string(qp.src[begin:qp.pos])
+}
+
+func (qp *queryParser) expect(rn rune) error {
+	if qp.peek() != rn {
+		if qp.eof() {
+			return qp.errf("expected '%c', got end of query", rn)
+		}
+		return qp.errf("expected '%c', got '%c'", rn, qp.peek())
+	}
+	qp.pos++
+	return nil
+}
+
+func (qp *queryParser) parse() ([]queryStep, error) {
+	var steps []queryStep
+	qp.skipSpace()
+	comb := combDescendant
+	for {
+		st, err := qp.step()
+		if err != nil {
+			return nil, err
+		}
+		st.comb = comb
+		steps = append(steps, st)
+
+		spaced := qp.skipSpace()
+		switch {
+		case qp.eof():
+			return steps, nil
+		case qp.peek() == '>':
+			qp.pos++
+			qp.skipSpace()
+			comb = combChild
+		case spaced:
+			comb = combDescendant
+		default:
+			return nil, qp.errf("unexpected '%c'", qp.peek())
+		}
+	}
+}
+
+// kind parses either a kind name or a kind number ("#12")
+func (qp *queryParser) kind() (FragmentKind, error) {
+	begin := qp.pos
+	if qp.peek() == '#' {
+		qp.pos++
+		digits := qp.pos
+		for !qp.eof() && unicode.IsDigit(qp.peek()) {
+			qp.pos++
+		}
+		kind, err := strconv.Atoi(string(qp.src[digits:qp.pos]))
+		if err != nil {
+			return 0, qp.errf("expected kind number")
+		}
+		return FragmentKind(kind), nil
+	}
+	name := qp.ident()
+	if name == "" {
+		if qp.eof() {
+			return 0, qp.errf("expected kind, got end of query")
+		}
+		return 0, qp.errf("expected kind, got '%c'", qp.peek())
+	}
+	kind, ok := qp.kinds.Kind(name)
+	if !ok {
+		qp.pos = begin
+		return 0, qp.errf("unknown kind name %q", name)
+	}
+	return kind, nil
+}
+
+func (qp *queryParser) step() (queryStep, error) {
+	st := queryStep{}
+	if qp.peek() == '*' {
+		qp.pos++
+		st.any = true
+	} else {
+		kind, err := qp.kind()
+		if err != nil {
+			return st, err
+		}
+		st.kind = kind
+	}
+
+	// Parse the suffixes
+	for {
+		switch qp.peek() {
+		case '@':
+			qp.pos++
+			if st.capture = qp.ident(); st.capture == "" {
+				return st, qp.errf("expected capture name")
+			}
+		case '[':
+			qp.pos++
+			pred, err := qp.predicate()
+			if err != nil {
+				return st, err
+			}
+			st.predicates = append(st.predicates, pred)
+		case ':':
+			qp.pos++
+			outside, err := qp.outside()
+			if err != nil {
+				return st, err
+			}
+			st.outside = append(st.outside, outside...)
+		default:
+			return st, nil
+		}
+	}
+}
+
+func (qp *queryParser) predicate() (func(string) bool, error) {
+	qp.skipSpace()
+	begin := qp.pos
+	if name := qp.ident(); name != "text" {
+		qp.pos = begin
+		return nil, qp.errf("unknown predicate subject %q", name)
+	}
+	qp.skipSpace()
+
+	var op string
+	for _, candidate := range []string{"!=", "^=", "$=", "*=", "~=", "="} {
+		if strings.HasPrefix(string(qp.src[qp.pos:]), candidate) {
+			op = candidate
+			break
+		}
+	}
+	if op == "" {
+		return nil, qp.errf("expected predicate operator")
+	}
+	qp.pos += len(op)
+	qp.skipSpace()
+
+	str, err := qp.str()
+	if err != nil {
+		return nil, err
+	}
+	qp.skipSpace()
+	if err := qp.expect(']'); err != nil {
+		return nil, err
+	}
+
+	switch op {
+	case "!=":
+		return func(src string) bool { return src != str }, nil
+	case "^=":
+		return func(src string) bool { return strings.HasPrefix(src, str) }, nil
+	case "$=":
+		return func(src string) bool { return strings.HasSuffix(src, str) }, nil
+	case "*=":
+		return func(src string) bool { return strings.Contains(src, str) }, nil
+	case "~=":
+		exp, err := regexp.Compile(str)
+		if err != nil {
+			return nil, qp.errf("invalid regular expression: %s", err)
+		}
+		return exp.MatchString, nil
+	}
+	return func(src string) bool { return src == str }, nil
+}
+
+// str parses a double-quoted string literal
+func (qp *queryParser) str() (string, error) {
+	begin := qp.pos
+	if err := qp.expect('"'); err != nil {
+		return "", err
+	}
+	for !qp.eof() && qp.peek() != '"' {
+		if qp.peek() == '\\' {
+			qp.pos++
+		}
+		qp.pos++
+	}
+	if err := qp.expect('"'); err != nil {
+		return "", err
+	}
+	str, err := strconv.Unquote(string(qp.src[begin:qp.pos]))
+	if err != nil {
+		qp.pos = begin
+		return "", qp.errf("invalid string literal")
+	}
+	return str, nil
+}
+
+func (qp *queryParser) outside() ([]FragmentKind, error) {
+	begin := qp.pos
+	if name := qp.ident(); name != "outside" {
+		qp.pos = begin
+		return nil, qp.errf("unknown pseudo-class %q", name)
+	}
+	if err := qp.expect('('); err != nil {
+		return nil, err
+	}
+	var kinds []FragmentKind
+	for {
+		qp.skipSpace()
+		kind, err := qp.kind()
+		if err != nil {
+			return nil, err
+		}
+		kinds = append(kinds, kind)
+		qp.skipSpace()
+		if qp.peek() != ',' {
+			break
+		}
+		qp.pos++
+	}
+	if err := qp.expect(')'); err != nil {
+		return nil, err
+	}
+	return kinds, nil
+}
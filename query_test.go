@@ -0,0 +1,162 @@
+package parser_test
+
+import (
+	"testing"
+
+	llp "github.com/romshark/llparser"
+	"github.com/stretchr/testify/require"
+)
+
+func matchSrc(matches []llp.QueryMatch) []string {
+	src := make([]string, len(matches))
+	for ix, match := range matches {
+		src[ix] = string(match.Fragment.Src())
+	}
+	return src
+}
+
+func TestQuery(t *testing.T) {
+	pr := newParser(t, newFunctionGrammar(), nil)
+	mainFrag := mustParse(t, pr, newSource(`fn f{a b \{c \{d}} e} fn g{h}`))
+
+	for query, expected := range map[string][]string{
+		"word":                   {"f", "a", "b", "c", "d", "e", "g", "h"},
+		"function > word":        {"f", "g"},
+		"function > body > word": {"a", "b", "e", "h"},
+		"function body word":     {"a", "b", "c", "d", "e", "h"},
+		"lambda word":            {"c", "d"},
+		"lambda > body > word":   {"c", "d"},
+		"lambda lambda word":     {"d"},
+		"function word:outside(lambda)": {
+			"f", "a", "b", "e", "g", "h",
+		},
+		"body word:outside(lambda)":     {"a", "b", "c", "d", "e", "h"},
+		"word:outside(lambda)":          {"f", "a", "b", "e", "g", "h"},
+		"lambda > * > word":             {"c", "d"},
+		"#401 > #3":                     {"a", "b", "c", "d", "e", "h"},
+		`word[text="e"]`:                {"e"},
+		`word[text!="e"][text^="g"]`:    {"g"},
+		`function[text$="h}"] word`:     {"g", "h"},
+		`function[text*="\\{d"] > word`: {"f"},
+		`word[ text ~= "^[b-d]$" ]`:     {"b", "c", "d"},
+		"lambda":                        {`\{c \{d}}`, `\{d}`},
+		"body > lambda > body > lambda": {`\{d}`},
+		"function > lambda":             {},
+	} {
+		t.Run(query, func(t *testing.T) {
+			matches := queryMatches(t, query, mainFrag)
+			require.Equal(t, expected, matchSrc(matches))
+		})
+	}
+}
+
+func TestQueryCaptures(t *testing.T) {
+	pr := newParser(t, newFunctionGrammar(), nil)
+	mainFrag := mustParse(t, pr, newSource(`fn f{a \{b}} fn g{c}`))
+
+	t.Run("Single", func(t *testing.T) {
+		matches := queryMatches(
+			t,
+			"function@fn > body > word@id",
+			mainFrag,
+		)
+		require.Len(t, matches, 2)
+		require.Equal(t, "a", string(matches[0].Captures["id"].Src()))
+		require.Equal(t, matches[0].Fragment, matches[0].Captures["id"])
+		require.Equal(
+			t,
+			`fn f{a \{b}}`,
+			string(matches[0].Captures["fn"].Src()),
+		)
+		require.Equal(t, "c", string(matches[1].Captures["id"].Src()))
+		require.Equal(t, "fn g{c}", string(matches[1].Captures["fn"].Src()))
+	})
+
+	t.Run("Reused", func(t *testing.T) {
+		// The capture of the outermost step reusing a name is kept
+		var captured []string
+		for _, match := range queryMatches(t, "body@x word@x", mainFrag) {
+			require.Len(t, match.Captures, 1)
+			captured = append(captured, string(match.Captures["x"].Src()))
+		}
+		require.Equal(t, []string{"a \\{b}", "b", "a \\{b}", "c"}, captured)
+	})
+
+	t.Run("Deduplicated", func(t *testing.T) {
+		// Without captures every fragment is matched once
+		// even though "b" is nested in multiple bodies
+		require.Equal(
+			t,
+			[]string{"a", "b", "b", "c"},
+			matchSrc(queryMatches(t, "body@b word", mainFrag)),
+		)
+		require.Equal(
+			t,
+			[]string{"a", "b", "c"},
+			matchSrc(queryMatches(t, "body word", mainFrag)),
+		)
+	})
+}
+
+func TestQueryCompileErr(t *testing.T) {
+	for query, expected := range map[string]string{
+		"":                      "offset 0: expected kind, got end of query",
+		"unknown":               `offset 0: unknown kind name "unknown"`,
+		"function >":            "offset 10: expected kind, got end of query",
+		"function,body":         "offset 8: unexpected ','",
+		"function@":             "offset 9: expected capture name",
+		"function[src=\"x\"]":   `offset 9: unknown predicate subject "src"`,
+		"function[text==\"x\"]": "offset 14: expected '\"', got '='",
+		"function[text=\"x\"":   "offset 17: expected ']', got end of query",
+		"function[text=\"x]":    "offset 17: expected '\"', got end of query",
+		"function[text~=\"(\"]": "offset 19: invalid regular expression: " +
+			"error parsing regexp: missing closing ): `(`",
+		"function:inside(body)": `offset 9: unknown pseudo-class "inside"`,
+		"function:outside(body": "offset 21: expected ')', got end of query",
+		"function:outside()":    "offset 17: expected kind, got ')'",
+		"#x":                    "offset 1: expected kind number",
+	} {
+		t.Run(query, func(t *testing.T) {
+			q, err := llp.CompileQuery(query, newKinds(t))
+			require.Error(t, err)
+			require.Equal(t, "invalid query at "+expected, err.Error())
+			require.Nil(t, q)
+		})
+	}
+
+	require.Panics(t, func() { llp.MustCompileQuery("unknown", nil) })
+}
+
+func TestKindRegistry(t *testing.T) {
+	kinds := &llp.KindRegistry{}
+	require.NoError(t, kinds.Register(kindFunction, "function"))
+
+	name, ok := kinds.Name(kindFunction)
+	require.True(t, ok)
+	require.Equal(t, "function", name)
+
+	kind, ok := kinds.Kind("function")
+	require.True(t, ok)
+	require.Equal(t, kindFunction, kind)
+
+	_, ok = kinds.Name(kindBody)
+	require.False(t, ok)
+	_, ok = kinds.Kind("body")
+	require.False(t, ok)
+
+	require.Equal(
+		t,
+		`kind 400 already registered as "function"`,
+		kinds.Register(kindFunction, "other").Error(),
+	)
+	require.Equal(
+		t,
+		`name "function" already registered for kind 400`,
+		kinds.Register(kindBody, "function").Error(),
+	)
+	require.Equal(
+		t,
+		"missing name of kind 401",
+		kinds.Register(kindBody, "").Error(),
+	)
+}
@@ -0,0 +1,350 @@
+package parser
+
+// Kinds of the fragments produced by tolerant parses (see ParseOptions)
+const (
+	// KindError is the kind of tokens covering skipped source code
+	KindError FragmentKind = -1
+
+	// KindMissing is the kind of empty tokens
+	// representing expected but absent fragments
+	KindMissing FragmentKind = -2
+)
+
+// failurePos returns the index of the source file a syntax error
+// occurred at. Returns false for errors other than syntax errors
+func failurePos(err error, file *SourceFile) (uint, bool) {
+	switch err := err.(type) {
+	case *ErrUnexpectedToken:
+		return err.At.Index, true
+	case errEOF:
+		return uint(len(file.Src)), true
+	}
+	return 0, false
+}
+
+// recoverable returns the position of the syntax error
+// if the parser recovers from errors at that position
+func (ctx *Context) recoverable(err error, file *SourceFile) (uint, bool) {
+	if !ctx.tolerant() {
+		return 0, false
+	}
+	at, ok := failurePos(err, file)
+	return at, ok && ctx.recovery[at]
+}
+
+// fail records the mismatch of a terminal at the given position
+func (ctx *Context) fail(at Cursor) {
+	if ctx.speculating == 0 && at.Index > ctx.farthest {
+		ctx.farthest = at.Index
+	}
+}
+
+// tolerant returns true if the parser recovers from syntax errors
+func (ctx *Context) tolerant() bool {
+	return ctx.recovery != nil && ctx.speculating == 0
+}
+
+// matches returns true if the pattern matches at least one rune at the
+// given position. All side effects of the match are discarded
+func (pr Parser) matches(
+	ctx *Context,
+	scan *scanner,
+	pattern Pattern,
+	at Cursor,
+	level uint,
+) bool {
+	lexer := scan.Lexer
+	before, mk := lexer.cr, ctx.mark()
+	lexer.cr = at
+	ctx.speculating++
+	ctx.pushChoice()
+	_, err := pr.handlePattern(nil, ctx, scan.New(), pattern, level+1)
+	ctx.popChoice()
+	ctx.speculating--
+	matched := err == nil && lexer.cr.Index > at.Index
+	ctx.rewind(mk)
+	lexer.cr = before
+	return matched
+}
+
+// followed returns true if any pattern following the current one
+// in any of the enclosing sequences or repetitions matches
+func (pr Parser) followed(
+	ctx *Context,
+	scan *scanner,
+	at Cursor,
+	level uint,
+) bool {
+	follow := ctx.follow
+	for ix := len(follow) - 1; ix >= 0; ix-- {
+		for _, pt := range follow[ix].patterns {
+			if pr.matches(ctx, scan, pt, at, level) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// failureDiagnostic returns the diagnostic of a syntax error
+// preferring the errors of the error-rules of failed rules
+func (pr Parser) failureDiagnostic(ctx *Context, failure error) Diagnostic {
+	err, ok := failure.(*ErrUnexpectedToken)
+	if !ok {
+		return Diagnostic{
+			Code:    CodeUnexpectedToken,
+			Message: "unexpected end of file",
+		}
+	}
+	if ruleErr := ctx.ruleErr(err); ruleErr != nil {
+		return *ToDiagnostic(ruleErr)
+	}
+	err.kinds = pr.Kinds.or()
+	err.Suggestions = suggest(err)
+	return *ToDiagnostic(err)
+}
+
+// reportRecovery reports the diagnostic of a recovery unless it's located
+// at the end of the previous diagnostic suppressing cascading diagnostics
+func reportRecovery(ctx *Context, diag Diagnostic) {
+	if n := len(ctx.diagnostics); n > 0 &&
+		ctx.diagnostics[n-1].End.Index == diag.Begin.Index {
+		return
+	}
+	ctx.Report(diag)
+}
+
+// skip covers the source code between the cursors by an error token
+func (pr Parser) skip(
+	ctx *Context,
+	scan *scanner,
+	begin Cursor,
+	end Cursor,
+	failure error,
+) {
+	scan.Lexer.cr = end
+	if end.Index == begin.Index {
+		return
+	}
+	scan.record(&Token{VKind: KindError, VBegin: begin, VEnd: end}, "")
+	diag := pr.failureDiagnostic(ctx, failure)
+	diag.Begin, diag.End = begin, end
+	reportRecovery(ctx, diag)
+}
+
+// insertMissing inserts an empty token representing the absent pattern
+func (pr Parser) insertMissing(
+	ctx *Context,
+	scan *scanner,
+	pattern Pattern,
+	at Cursor,
+) {
+	scan.record(&Token{VKind: KindMissing, VBegin: at, VEnd: at}, "")
+	name := designation(pattern, pr.Kinds.or())
+	if name == "" {
+		name = "token"
+	}
+	reportRecovery(ctx, Diagnostic{
+		Code:    CodeMissing,
+		Message: "missing " + name,
+		Begin:   at,
+		End:     at,
+	})
+}
+
+// recoverSequence recovers from the failure of the element of a sequence
+// by skipping source code until either the element or any following
+// pattern matches. Elements that are followed immediately are considered
+// missing. Returns the index of the element to continue with
+func (pr Parser) recoverSequence(
+	ctx *Context,
+	scan *scanner,
+	patterns Sequence,
+	ix int,
+	cp checkpoint,
+	failure error,
+	level uint,
+) (int, bool) {
+	begin, file := cp.cr, cp.cr.File
+	at, ok := ctx.recoverable(failure, file)
+	if !ok || at < begin.Index {
+		return 0, false
+	}
+	ctx.backtrack(cp, scan)
+	if at == begin.Index && ctx.repair > 0 {
+		if next, ok := pr.repair(ctx, scan, patterns, ix, begin, level); ok {
+			return next, true
+		}
+	}
+
+	end := uint(len(file.Src))
+	for pos := at; ; pos++ {
+		cr := file.cursor(pos)
+		if pos > begin.Index && pos < end &&
+			pr.matches(ctx, scan, patterns[ix], cr, level) {
+			pr.skip(ctx, scan, begin, cr, failure)
+			return ix, true
+		}
+		if pos >= end || pr.followed(ctx, scan, cr, level) {
+			pr.skip(ctx, scan, begin, cr, failure)
+			pr.insertMissing(ctx, scan, patterns[ix], cr)
+			return ix + 1, true
+		}
+	}
+}
+
+// recoverRepetition recovers from the failure of an iteration by skipping
+// source code until either another iteration or, if the repetition may end,
+// any following pattern matches. Returns true if the repetition continues
+func (pr Parser) recoverRepetition(
+	ctx *Context,
+	scan *scanner,
+	repeated *Repeated,
+	cp checkpoint,
+	failure error,
+	mayEnd bool,
+	level uint,
+) (recovered, resume bool) {
+	begin, file := cp.cr, cp.cr.File
+	at, ok := ctx.recoverable(failure, file)
+	if !ok || at < begin.Index {
+		return false, false
+	}
+
+	end := uint(len(file.Src))
+	for pos := at; pos <= end; pos++ {
+		if pos == begin.Index {
+			// Nothing to skip
+			continue
+		}
+		cr := file.cursor(pos)
+		if pos < end && pr.matches(ctx, scan, repeated.Pattern, cr, level) {
+			ctx.backtrack(cp, scan)
+			pr.skip(ctx, scan, begin, cr, failure)
+			return true, true
+		}
+		if mayEnd && (pos == end || pr.followed(ctx, scan, cr, level)) {
+			ctx.backtrack(cp, scan)
+			pr.skip(ctx, scan, begin, cr, failure)
+			return true, false
+		}
+	}
+	return false, false
+}
+
+// DefaultMaxRecoveries defines the maximum number of positions
+// a tolerant parse recovers at when ParseOptions.MaxRecoveries is 0
+const DefaultMaxRecoveries = 100
+
+// parseTolerant parses the source file recovering from syntax errors.
+// The source file is parsed repeatedly recovering at all positions
+// previous attempts failed at until the parse succeeds
+// or the maximum number of recoveries is reached
+func (pr *Parser) parseTolerant(
+	source *SourceFile,
+	debug *DebugProfile,
+	options ParseOptions,
+) (*Result, error) {
+	maxRecoveries := options.MaxRecoveries
+	if maxRecoveries < 1 {
+		maxRecoveries = DefaultMaxRecoveries
+	}
+	recovery := map[uint]bool{}
+	var ruleErrs map[uint]error
+	for {
+		if pr.MaxRecursionLevel > 0 {
+			pr.recursionRegister.Reset()
+		}
+		lex := &lexer{cr: NewCursor(source)}
+		ctx := newContext(options.Value, pr.DeferActions)
+		ctx.recovery, ctx.ruleErrs = recovery, ruleErrs
+		ctx.repair = options.RepairTokens
+
+		scan := newScanner(lex)
+		scan.NoTree = options.NoTree
+		scan.Lossless = options.Lossless
+		mainFrag, err := pr.parseRule(debug, ctx, scan, pr.grammar, 0)
+		ruleErrs = ctx.ruleErrs
+
+		if err == nil {
+			// Ensure EOF
+			last, _ := lex.ReadUntil(
+				func(uint, Cursor) bool { return true },
+				0,
+			)
+			if last == nil {
+				if err := ctx.commit(); err != nil {
+					return nil, err
+				}
+				return &Result{
+					Fragment:    mainFrag,
+					Diagnostics: ctx.diagnostics,
+				}, nil
+			}
+			err = &ErrUnexpectedToken{At: last.VBegin}
+		}
+
+		at, ok := failurePos(err, source)
+		if !ok {
+			return nil, err
+		}
+		if uint(len(recovery)) < maxRecoveries {
+			if !recovery[ctx.farthest] {
+				// Recover at the farthest mismatch first since
+				// the error may have been swallowed by a repetition
+				recovery[ctx.farthest] = true
+				continue
+			}
+			if !recovery[at] {
+				recovery[at] = true
+				continue
+			}
+		}
+
+		// Recovering didn't get past the error or the maximum number
+		// of recoveries was reached, cover the rest of the source file
+		// by an error token
+		if mainFrag == nil {
+			ctx.rewind(mark{})
+			begin := NewCursor(source)
+			mainFrag = &Token{
+				VKind:  pr.grammar.Kind,
+				VBegin: begin,
+				VEnd:   begin,
+			}
+		}
+		rest := &Token{
+			VKind:  KindError,
+			VBegin: mainFrag.End(),
+			VEnd:   source.cursor(uint(len(source.Src))),
+		}
+		var root Fragment = &Token{
+			VKind:  mainFrag.Kind(),
+			VBegin: mainFrag.Begin(),
+			VEnd:   rest.VEnd,
+		}
+		if !options.NoTree {
+			elements := mainFrag.Elements()
+			ct := &Construct{
+				Token: root.(*Token),
+				VElements: append(
+					elements[:len(elements):len(elements)],
+					rest,
+				),
+			}
+			if original, ok := mainFrag.(*Construct); ok {
+				ct.VLabels = original.VLabels
+			}
+			root = ct
+		}
+		if rest.VEnd.Index > rest.VBegin.Index {
+			diag := pr.failureDiagnostic(ctx, err)
+			diag.Begin, diag.End = rest.VBegin, rest.VEnd
+			reportRecovery(ctx, diag)
+		}
+		if err := ctx.commit(); err != nil {
+			return nil, err
+		}
+		return &Result{Fragment: root, Diagnostics: ctx.diagnostics}, nil
+	}
+}
@@ -0,0 +1,148 @@
+package parser_test
+
+import (
+	"errors"
+	"testing"
+
+	llp "github.com/romshark/llparser"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReduce(t *testing.T) {
+	pr := newParser(t, newCalcGrammar(), nil)
+
+	for src, expected := range map[string]int{
+		"42":          42,
+		"1+2*3":       7,
+		"(1+2)*3":     9,
+		"2*(3+4)*5+1": 71,
+	} {
+		t.Run(src, func(t *testing.T) {
+			result, err := pr.ParseWith(newSource(src), llp.ParseOptions{})
+			require.NoError(t, err)
+			require.Equal(t, expected, result.Value)
+			require.IsType(t, &llp.Construct{}, result.Fragment)
+			require.NotEmpty(t, result.Fragment.Elements())
+
+			result, err = pr.ParseWith(
+				newSource(src),
+				llp.ParseOptions{NoTree: true},
+			)
+			require.NoError(t, err)
+			require.Equal(t, expected, result.Value)
+			require.IsType(t, &llp.Token{}, result.Fragment)
+			require.Equal(t, src, string(result.Fragment.Src()))
+		})
+	}
+}
+
+func TestReduceNoReducer(t *testing.T) {
+	pr := newParser(t, &llp.Rule{
+		Designation: "main",
+		Pattern:     llp.Sequence{testR_foo, testR_bar},
+	}, nil)
+
+	result, err := pr.ParseWith(newSource("foobar"), llp.ParseOptions{})
+	require.NoError(t, err)
+	require.Nil(t, result.Value)
+}
+
+func TestReduceBacktracking(t *testing.T) {
+	var reduced [][]string
+	collect := func(
+		_ *llp.Context,
+		_ llp.Fragment,
+		values []interface{},
+	) (interface{}, error) {
+		strs := make([]string, len(values))
+		for ix, val := range values {
+			strs[ix] = string(val.(*llp.Token).Src())
+		}
+		reduced = append(reduced, strs)
+		return strs, nil
+	}
+
+	pr := newParser(t, &llp.Rule{
+		Designation: "main",
+		Reduce:      collect,
+		Pattern: llp.Sequence{
+			llp.Either{
+				llp.Sequence{testR_foo, testR_bar, testR_foo},
+				llp.Sequence{testR_foo, testR_bar},
+			},
+			&llp.Repeated{Pattern: llp.Sequence{termSeparator, termLatinWord}},
+		},
+	}, nil)
+
+	for _, noTree := range []bool{false, true} {
+		reduced = nil
+		result, err := pr.ParseWith(
+			newSource("foobar,baz,qux"),
+			llp.ParseOptions{NoTree: noTree},
+		)
+		require.NoError(t, err)
+		require.Equal(
+			t,
+			[]string{"foo", "bar", ",", "baz", ",", "qux"},
+			result.Value,
+		)
+		require.Len(t, reduced, 1)
+	}
+}
+
+func TestReduceNoTreeAction(t *testing.T) {
+	var actionFrags []llp.Fragment
+	pr := newParser(t, &llp.Rule{
+		Designation: "main",
+		Pattern: llp.Sequence{
+			testR_foo,
+			&llp.Rule{
+				Designation: "bar",
+				Kind:        100,
+				Pattern:     testR_bar,
+				Action: func(_ *llp.Context, f llp.Fragment) error {
+					actionFrags = append(actionFrags, f)
+					return nil
+				},
+			},
+		},
+	}, nil)
+
+	src := newSource("foobar")
+	result, err := pr.ParseWith(src, llp.ParseOptions{NoTree: true})
+	require.NoError(t, err)
+	require.IsType(t, &llp.Token{}, result.Fragment)
+	checkFrag(t, src, result.Fragment, 0, C{1, 1}, C{1, 7}, 0)
+
+	require.Len(t, actionFrags, 1)
+	require.IsType(t, &llp.Token{}, actionFrags[0])
+	checkFrag(t, src, actionFrags[0], 100, C{1, 4}, C{1, 7}, 0)
+}
+
+func TestReduceErr(t *testing.T) {
+	expectedErr := errors.New("custom error")
+	pr := newParser(t, &llp.Rule{
+		Designation: "main",
+		Pattern: llp.Sequence{
+			testR_foo,
+			&llp.Rule{
+				Designation: "bar",
+				Pattern:     testR_bar,
+				Reduce: func(
+					*llp.Context,
+					llp.Fragment,
+					[]interface{},
+				) (interface{}, error) {
+					return nil, expectedErr
+				},
+			},
+		},
+	}, nil)
+
+	result, err := pr.ParseWith(newSource("foobar"), llp.ParseOptions{})
+	require.Error(t, err)
+	require.IsType(t, &llp.Err{}, err)
+	require.Equal(t, expectedErr, err.(*llp.Err).Err)
+	require.Equal(t, "custom error at test.txt:1:4", err.Error())
+	require.Nil(t, result)
+}
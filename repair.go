@@ -0,0 +1,183 @@
+package parser
+
+// following represents the patterns following the current ones
+// in an enclosing sequence or repetition
+type following struct {
+	patterns []Pattern
+
+	// repeated is true if the patterns are optional iterations
+	// of an enclosing repetition
+	repeated bool
+}
+
+// insertion represents an insertable exact terminal
+// and the rules enclosing it from the outermost to the innermost one
+type insertion struct {
+	exact *Exact
+	rules []*Rule
+}
+
+// fragment returns the empty fragment representing the insertion at
+// the given position wrapping the token in constructs of the rules
+func (in insertion) fragment(at Cursor) Fragment {
+	var frag Fragment = &Token{VKind: in.exact.Kind, VBegin: at, VEnd: at}
+	for ix := len(in.rules) - 1; ix >= 0; ix-- {
+		frag = &Construct{
+			Token:     &Token{VKind: in.rules[ix].Kind, VBegin: at, VEnd: at},
+			VElements: []Fragment{frag},
+		}
+	}
+	return frag
+}
+
+// insertable returns the exact terminals each matching the pattern on its
+// own including those of referenced rules without following cyclic rules
+func insertable(
+	pattern Pattern,
+	rules []*Rule,
+	insertions []insertion,
+) []insertion {
+	switch pt := pattern.(type) {
+	case *Exact:
+		return append(insertions, insertion{exact: pt, rules: rules})
+	case Either:
+		for _, option := range pt {
+			insertions = insertable(option, rules, insertions)
+		}
+	case *Rule:
+		for _, rule := range rules {
+			if rule == pt {
+				return insertions
+			}
+		}
+		enclosing := make([]*Rule, len(rules), len(rules)+1)
+		copy(enclosing, rules)
+		return insertable(pt.Pattern, append(enclosing, pt), insertions)
+	case Label:
+		return insertable(pt.Pattern, rules, insertions)
+	case Shaped:
+		return insertable(pt.Pattern, rules, insertions)
+	}
+	return insertions
+}
+
+// tokenEnd returns the end of the token at the given index
+// which is either a run of word runes or any other single rune
+func tokenEnd(src []rune, index uint) uint {
+	end := index + 1
+	if isWordRune(src[index]) {
+		for end < uint(len(src)) && isWordRune(src[end]) {
+			end++
+		}
+	}
+	return end
+}
+
+// advances returns true if the patterns followed by the patterns following
+// the current sequence match at least ctx.repair terminals at the given
+// position or reach the end of the source file.
+// All side effects of the match are discarded
+func (pr Parser) advances(
+	ctx *Context,
+	scan *scanner,
+	patterns []Pattern,
+	at Cursor,
+	level uint,
+) bool {
+	lexer := scan.Lexer
+	before, mk, tokens := lexer.cr, ctx.mark(), ctx.tokens
+	lexer.cr = at
+	ctx.speculating++
+	ctx.pushChoice()
+	defer func() {
+		ctx.popChoice()
+		ctx.speculating--
+		ctx.rewind(mk)
+		lexer.cr = before
+	}()
+
+	// The innermost following patterns are those of the current sequence
+	tails := []following{{patterns: patterns}}
+	for ix := len(ctx.follow) - 2; ix >= 0; ix-- {
+		tails = append(tails, ctx.follow[ix])
+	}
+	for _, tail := range tails {
+		for _, pt := range tail.patterns {
+			for {
+				start := lexer.cr
+				_, err := pr.handlePattern(nil, ctx, scan.New(), pt, level+1)
+				if ctx.tokens-tokens >= ctx.repair {
+					return true
+				}
+				if err != nil {
+					if !tail.repeated {
+						_, eof := err.(errEOF)
+						return eof
+					}
+					lexer.cr = start
+					break
+				}
+				if !tail.repeated || lexer.cr.Index == start.Index {
+					break
+				}
+			}
+		}
+	}
+	return lexer.reachedEOF()
+}
+
+// repair repairs the failure of the element of a sequence at the given
+// position either by inserting an expected exact terminal or by removing
+// the unexpected token. Returns the index of the element to continue with
+func (pr Parser) repair(
+	ctx *Context,
+	scan *scanner,
+	patterns Sequence,
+	ix int,
+	at Cursor,
+	level uint,
+) (int, bool) {
+	// The continuation after an insertion doesn't depend on the inserted
+	// terminal, so either all insertable terminals advance or none does
+	// and the first one is inserted
+	if insertions := insertable(patterns[ix], nil, nil); len(insertions) > 0 &&
+		pr.advances(ctx, scan, patterns[ix+1:], at, level) {
+		inserted := insertions[0]
+		scan.record(inserted.fragment(at), "")
+		text := string(inserted.exact.Expectation)
+		ctx.Report(Diagnostic{
+			Code:    CodeMissing,
+			Message: "inserted missing '" + text + "'",
+			Begin:   at,
+			End:     at,
+			Fixes: []Fix{{
+				Message: "insert '" + text + "'",
+				Edits:   []Edit{{Begin: at.Index, End: at.Index, Text: text}},
+			}},
+		})
+		return ix + 1, true
+	}
+
+	file := at.File
+	if at.Index >= uint(len(file.Src)) {
+		return 0, false
+	}
+	end := file.cursor(tokenEnd(file.Src, at.Index))
+	if !pr.advances(ctx, scan, patterns[ix:], end, level) {
+		return 0, false
+	}
+	scan.Lexer.cr = end
+	scan.record(&Token{VKind: KindError, VBegin: at, VEnd: end}, "")
+	text := string(file.Src[at.Index:end.Index])
+	ctx.Report(Diagnostic{
+		Code:    CodeUnexpectedToken,
+		Message: "removed unexpected '" + text + "'",
+		Begin:   at,
+		End:     end,
+		Fixes: []Fix{{
+			Message: "remove '" + text + "'",
+			Edits:   []Edit{{Begin: at.Index, End: end.Index}},
+		}},
+	})
+	return ix, true
+}
@@ -0,0 +1,117 @@
+package parser_test
+
+import (
+	"testing"
+
+	llp "github.com/romshark/llparser"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRepair(t *testing.T) {
+	pr := newParser(t, newCallGrammar(), nil)
+	pr.Kinds = newKinds(t)
+	options := llp.ParseOptions{RepairTokens: 3}
+
+	for _, tt := range []struct {
+		name  string
+		src   string
+		tree  string
+		diags []string
+		fixed string
+	}{
+		{
+			name: "Inserted",
+			src:  "f(x;g();",
+			tree: `(file (call (word "f") (separator "(") (word "x") ` +
+				`(separator "") (separator ";")) ` +
+				`(call (word "g") (separator "(") (separator ")") ` +
+				`(separator ";")))`,
+			diags: []string{"missing 3-3: inserted missing ')'"},
+			fixed: "f(x);g();",
+		},
+		{
+			name: "InsertedAtEOF",
+			src:  "f(x",
+			tree: `(file (call (word "f") (separator "(") (word "x") ` +
+				`(separator "") (separator "")))`,
+			diags: []string{
+				"missing 3-3: inserted missing ')'",
+				"missing 3-3: inserted missing ';'",
+			},
+			fixed: "f(x);",
+		},
+		{
+			name: "Removed",
+			src:  "f(x));g();",
+			tree: `(file (call (word "f") (separator "(") (word "x") ` +
+				`(separator ")") (error ")") (separator ";")) ` +
+				`(call (word "g") (separator "(") (separator ")") ` +
+				`(separator ";")))`,
+			diags: []string{"unexpected-token 4-5: removed unexpected ')'"},
+			fixed: "f(x);g();",
+		},
+		{
+			// Neither inserting nor removing a single token
+			// repairs the call, the source code is skipped instead
+			name: "Skipped",
+			src:  "f(x y z);",
+			tree: `(file (call (word "f") (separator "(") (word "x") ` +
+				`(error " y z") (separator ")") (separator ";")))`,
+			diags: []string{"unexpected-token 3-7: unexpected token"},
+			fixed: "f(x y z);",
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			tree, diags, fixed := parseTolerant(t, pr, tt.src, options)
+			require.Equal(t, tt.tree, tree)
+			require.Equal(t, tt.diags, diags)
+			require.Equal(t, tt.fixed, fixed)
+		})
+	}
+}
+
+func TestRepairLookahead(t *testing.T) {
+	pr := newParser(t, newCallGrammar(), nil)
+	pr.Kinds = newKinds(t)
+
+	// The inserted ')' is followed by only 3 matching terminals
+	const src = "f(x;g(;"
+	_, diags, _ := parseTolerant(t, pr, src, llp.ParseOptions{RepairTokens: 3})
+	require.Equal(t, "missing 3-3: inserted missing ')'", diags[0])
+
+	_, diags, _ = parseTolerant(t, pr, src, llp.ParseOptions{RepairTokens: 4})
+	require.Equal(t, "missing 3-3: missing ')'", diags[0])
+}
+
+// TestRepairRule tests inserting terminals wrapped in rules
+func TestRepairRule(t *testing.T) {
+	punct := func(str string) *llp.Exact {
+		return &llp.Exact{Kind: FrSeparator, Expectation: []rune(str)}
+	}
+	pr := newParser(t, &llp.Rule{
+		Designation: "list",
+		Kind:        kindList,
+		Pattern: llp.Sequence{
+			punct("("),
+			punct("a"),
+			&llp.Rule{
+				Designation: "group",
+				Kind:        kindGroup,
+				Pattern:     llp.Either{punct(")"), punct("]")},
+			},
+			punct(";"),
+		},
+	}, nil)
+	pr.Kinds = newKinds(t)
+
+	tree, diags, fixed := parseTolerant(
+		t, pr, "(a;", llp.ParseOptions{RepairTokens: 1},
+	)
+	require.Equal(t,
+		`(list (separator "(") (separator "a") `+
+			`(group (separator "")) (separator ";"))`,
+		tree,
+	)
+	require.Equal(t, []string{"missing 2-2: inserted missing ')'"}, diags)
+	require.Equal(t, "(a);", fixed)
+}
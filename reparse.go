@@ -0,0 +1,288 @@
+package parser
+
+import (
+	"errors"
+	"sort"
+)
+
+// Edit represents the replacement of a range of a source file by new text
+type Edit struct {
+	// Begin is the index of the first replaced rune
+	Begin uint
+
+	// End is the index following the last replaced rune.
+	// An edit inserts text when End equals Begin
+	End uint
+
+	// Text is the replacing text
+	Text string
+}
+
+// memoKey identifies a match of a rule at a position
+type memoKey struct {
+	rule  *Rule
+	begin uint
+}
+
+// memoEntry represents a recorded match of a rule
+type memoEntry struct {
+	// frag is the fragment of the rule which is located in the source file
+	// it was parsed from and must be shifted by delta runes
+	frag  Fragment
+	delta int
+
+	end uint
+
+	// examined is the index following the last rune examined by the rule
+	examined uint
+
+	// cut is true if the match passed a cut committing the choice point
+	// enclosing the rule
+	cut bool
+}
+
+// memoConfig holds the parser settings the recorded matches depend on
+type memoConfig struct {
+	grammar           *Rule
+	lineEndings       LineEndings
+	maxRecursionLevel uint
+}
+
+// memoConfig returns the current settings of the parser
+func (pr *Parser) memoConfig() memoConfig {
+	return memoConfig{
+		grammar:           pr.grammar,
+		lineEndings:       pr.LineEndings,
+		maxRecursionLevel: pr.MaxRecursionLevel,
+	}
+}
+
+// memo records the matches of rules during a parse to be reused
+// by subsequent reparses. Only matches of pure rules are recorded
+// since their matches depend on the source code they examined only
+type memo struct {
+	root    Fragment
+	options ParseOptions
+	config  memoConfig
+	file    *SourceFile
+	entries map[memoKey]memoEntry
+}
+
+func newMemo(
+	file *SourceFile,
+	options ParseOptions,
+	config memoConfig,
+) *memo {
+	return &memo{
+		options: options,
+		config:  config,
+		file:    file,
+		entries: map[memoKey]memoEntry{},
+	}
+}
+
+// record records the match of a rule. Empty matches aren't recorded
+// since reusing them would put the same fragment into the tree repeatedly
+func (mm *memo) record(
+	rule *Rule,
+	begin uint,
+	frag Fragment,
+	lx *lexer,
+	cut bool,
+) {
+	if lx.cr.Index == begin {
+		return
+	}
+	examined := lx.examined
+	if examined < begin {
+		examined = begin
+	}
+	mm.entries[memoKey{rule: rule, begin: begin}] = memoEntry{
+		frag:     frag,
+		end:      lx.cr.Index,
+		examined: examined,
+		cut:      cut,
+	}
+}
+
+// reuse advances the lexer over a recorded match of the rule at the
+// current position returning its fragment or nil if there's none
+// and whether the match passed a cut committing the enclosing choice point
+func (mm *memo) reuse(rule *Rule, lx *lexer) (Fragment, bool) {
+	entry, ok := mm.entries[memoKey{rule: rule, begin: lx.cr.Index}]
+	if !ok {
+		return nil, false
+	}
+	if entry.delta != 0 || entry.frag.Begin().File != mm.file {
+		entry.frag = relocate(entry.frag, entry.delta, mm.file)
+		entry.delta = 0
+		mm.entries[memoKey{rule: rule, begin: lx.cr.Index}] = entry
+	}
+	lx.cr = mm.file.cursor(entry.end)
+	if entry.examined > lx.examined {
+		lx.examined = entry.examined
+	}
+	return entry.frag, entry.cut
+}
+
+// shift returns the memo of the source file resulting from the given
+// edits keeping all matches that didn't examine any edited source code
+func (mm *memo) shift(
+	file *SourceFile,
+	edits []Edit,
+) *memo {
+	shifted := newMemo(file, mm.options, mm.config)
+	for key, entry := range mm.entries {
+		delta, valid := 0, true
+		for _, edit := range edits {
+			if edit.End <= key.begin {
+				delta += len([]rune(edit.Text)) - int(edit.End-edit.Begin)
+			} else if edit.Begin < entry.examined {
+				valid = false
+				break
+			}
+		}
+		if !valid {
+			continue
+		}
+		key.begin = uint(int(key.begin) + delta)
+		entry.delta += delta
+		entry.end = uint(int(entry.end) + delta)
+		entry.examined = uint(int(entry.examined) + delta)
+		shifted.entries[key] = entry
+	}
+	return shifted
+}
+
+// relocate copies the fragment shifting it by delta runes into the file
+func relocate(frag Fragment, delta int, file *SourceFile) Fragment {
+	tk := &Token{
+		VKind:  frag.Kind(),
+		VBegin: file.cursor(uint(int(frag.Begin().Index) + delta)),
+		VEnd:   file.cursor(uint(int(frag.End().Index) + delta)),
+	}
+	if isToken(frag) {
+		return tk
+	}
+	ct := &Construct{Token: tk}
+	if original, ok := frag.(*Construct); ok {
+		ct.VLabels = original.VLabels
+	}
+	if elements := frag.Elements(); len(elements) > 0 {
+		ct.VElements = make([]Fragment, len(elements))
+		for ix, el := range elements {
+			ct.VElements[ix] = relocate(el, delta, file)
+		}
+	}
+	return ct
+}
+
+// pureRule returns true if the matches of the rule depend on the examined
+// source code only, which is the case if neither the rule nor any of the
+// rules it refers to have actions, reducers, scopes or predicates.
+// Cuts don't affect successful matches other than by committing the
+// enclosing choice point which is recorded along with the match
+func pureRule(rule *Rule) bool {
+	rules := recursionRegister{}
+	findRules(rule, rules)
+	for rl := range rules {
+		if rl.Action != nil || rl.Undo != nil || rl.Reduce != nil ||
+			rl.Scoped || !purePattern(rl.Pattern) {
+			return false
+		}
+	}
+	return true
+}
+
+// purePattern returns true if the pattern contains no predicates
+// not considering referred rules
+func purePattern(pattern Pattern) bool {
+	switch pt := pattern.(type) {
+	case *Predicate:
+		return false
+	case Sequence:
+		for _, pt := range pt {
+			if !purePattern(pt) {
+				return false
+			}
+		}
+	case Either:
+		for _, pt := range pt {
+			if !purePattern(pt) {
+				return false
+			}
+		}
+	case Not:
+		return purePattern(pt.Pattern)
+	case Label:
+		return purePattern(pt.Pattern)
+	case Shaped:
+		return purePattern(pt.Pattern)
+	case *Repeated:
+		return purePattern(pt.Pattern)
+	}
+	return true
+}
+
+// Reparse parses the source code resulting from applying the given edits
+// to the source file of a parse-tree. The edits refer to the original
+// source code and must not overlap. The name of the source file is kept.
+//
+// When the parse-tree is the latest one produced by the parser and
+// incremental parsing is enabled (see Parser.Incremental) matches of rules
+// that didn't examine any edited source code are reused unless the
+// grammar, the line endings or the recursion limit of the parser changed
+// since. Rules that have actions, reducers, scopes or predicates or refer
+// to such rules are always reparsed. Lexed patterns are assumed to examine
+// only the runes of their token, the rune following it and as many runes
+// as their Lookahead permits. The resulting parse-tree is identical to the
+// one of a full parse using the options of the original parse.
+//
+// WARNING: Reparse isn't safe for concurrent use and shall therefore
+// not be executed by multiple goroutines concurrently!
+func (pr *Parser) Reparse(oldTree Fragment, edits []Edit) (Fragment, error) {
+	original := oldTree.Begin().File
+	sorted := make([]Edit, len(edits))
+	copy(sorted, edits)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Begin < sorted[j].Begin
+	})
+
+	// Apply the edits
+	var src []rune
+	pos := uint(0)
+	for _, edit := range sorted {
+		switch {
+		case edit.End < edit.Begin || edit.End > uint(len(original.Src)):
+			return nil, errors.New("edit out of range")
+		case edit.Begin < pos:
+			return nil, errors.New("overlapping edits")
+		}
+		src = append(src, original.Src[pos:edit.Begin]...)
+		src = append(src, []rune(edit.Text)...)
+		pos = edit.End
+	}
+	src = append(src, original.Src[pos:]...)
+	file := &SourceFile{
+		Name:        original.Name,
+		Src:         src,
+		TabWidth:    original.TabWidth,
+		LineEndings: pr.LineEndings,
+	}
+
+	options := ParseOptions{}
+	var mm *memo
+	if pr.memo != nil && pr.memo.root == oldTree {
+		options = pr.memo.options
+		// The source code of normalized files is already normalized
+		options.NormalizeLineEndings = false
+		if pr.memo.config == pr.memoConfig() {
+			mm = pr.memo.shift(file, sorted)
+		}
+	}
+	result, err := pr.parse(file, nil, options, mm)
+	if err != nil {
+		return nil, err
+	}
+	return result.Fragment, nil
+}
@@ -0,0 +1,235 @@
+package parser_test
+
+import (
+	"strings"
+	"testing"
+	"unicode"
+
+	llp "github.com/romshark/llparser"
+	"github.com/stretchr/testify/require"
+)
+
+// newGroupGrammar creates a grammar of words nested in parenthesized groups
+// such as "a (b (c d)) e" counting the invocations of the word lexer
+func newGroupGrammar(calls *int) *llp.Rule {
+	word := &llp.Rule{
+		Designation: "word",
+		Kind:        FrWord,
+		Pattern: &llp.Lexed{
+			Kind:   FrWord,
+			MinLen: 1,
+			Fn: func(_ uint, cr llp.Cursor) bool {
+				*calls++
+				return unicode.IsLetter(cr.File.Src[cr.Index])
+			},
+		},
+	}
+	items := &llp.Repeated{}
+	group := &llp.Rule{
+		Designation: "group",
+		Kind:        kindGroup,
+		Pattern: llp.Sequence{
+			&llp.Exact{Expectation: []rune("(")},
+			items,
+			&llp.Exact{Expectation: []rune(")")},
+		},
+	}
+	items.Pattern = llp.Either{group, word, termSpace}
+	return &llp.Rule{Designation: "list", Kind: kindList, Pattern: items}
+}
+
+func TestReparse(t *testing.T) {
+	for name, test := range map[string]struct {
+		Src   string
+		Edits []llp.Edit
+	}{
+		"Rename":  {"a (b c) d", []llp.Edit{{Begin: 3, End: 4, Text: "bb"}}},
+		"Append":  {"a (b c) d", []llp.Edit{{Begin: 4, End: 4, Text: "x"}}},
+		"Prepend": {"a (b c) d", []llp.Edit{{Begin: 3, End: 3, Text: "x"}}},
+		"Merge":   {"a (b c) d", []llp.Edit{{Begin: 4, End: 5}}},
+		"Split":   {"a (bc) d", []llp.Edit{{Begin: 4, End: 4, Text: " "}}},
+		"Begin":   {"a (b c) d", []llp.Edit{{Begin: 0, End: 0, Text: "(x) "}}},
+		"End":     {"a (b c) d", []llp.Edit{{Begin: 9, End: 9, Text: "e"}}},
+		"Delete":  {"a (b c) d", []llp.Edit{{Begin: 2, End: 8}}},
+		"Nest": {"a (b c) d", []llp.Edit{
+			{Begin: 5, End: 5, Text: "("},
+			{Begin: 6, End: 6, Text: ")"},
+		}},
+		"Multiple": {"a (b c)\n(d) e", []llp.Edit{
+			{Begin: 12, End: 13, Text: "f g"},
+			{Begin: 0, End: 1, Text: "(h)"},
+			{Begin: 9, End: 9, Text: "\n"},
+		}},
+	} {
+		t.Run(name, func(t *testing.T) {
+			calls := 0
+			pr := newParser(t, newGroupGrammar(&calls), nil)
+			pr.Incremental = true
+			oldTree, err := pr.Parse(newSource(test.Src))
+			require.NoError(t, err)
+
+			newTree, err := pr.Reparse(oldTree, test.Edits)
+			require.NoError(t, err)
+
+			full := newParser(t, newGroupGrammar(&calls), nil)
+			expected, err := full.Parse(newSource(string(newTree.Src())))
+			require.NoError(t, err)
+			requireSameTree(t, expected, newTree)
+			CheckCursor(t, newTree.Begin().File, newTree.Begin(), 1, 1)
+			require.Equal(t, "test.txt", newTree.Begin().File.Name)
+		})
+	}
+}
+
+func TestReparseReuse(t *testing.T) {
+	calls := 0
+	pr := newParser(t, newGroupGrammar(&calls), nil)
+	pr.Incremental = true
+	src := strings.Repeat("(alpha (beta gamma) delta)\n", 100)
+	tree, err := pr.Parse(newSource(src))
+	require.NoError(t, err)
+	fullCalls := calls
+
+	// Editing the same file repeatedly only reparses the edited groups
+	for ix, edit := range []llp.Edit{
+		{Begin: 8, End: 12, Text: "b"},
+		{Begin: 500, End: 500, Text: "x"},
+		{Begin: 0, End: 0, Text: "omega"},
+	} {
+		calls = 0
+		tree, err = pr.Reparse(tree, []llp.Edit{edit})
+		require.NoError(t, err, "edit %d", ix)
+		require.True(t, calls < fullCalls/10, "%d calls", calls)
+
+		expected, err := newParser(t, newGroupGrammar(&calls), nil).Parse(
+			newSource(string(tree.Src())),
+		)
+		require.NoError(t, err)
+		requireSameTree(t, expected, tree)
+	}
+	require.True(t, strings.HasPrefix(
+		string(tree.Src()),
+		"omega(alpha (b gamma) delta)\n",
+	))
+
+	// Trees other than the latest one are reparsed entirely
+	pr.Incremental = false
+	other, err := pr.Parse(newSource(src))
+	require.NoError(t, err)
+	calls = 0
+	_, err = pr.Reparse(other, []llp.Edit{{Begin: 0, End: 0, Text: "x"}})
+	require.NoError(t, err)
+	require.True(t, calls > fullCalls/2, "%d calls", calls)
+}
+
+// TestReparseLookahead tests reparsing matches of lexed patterns
+// that look ahead of the rune they stop at
+func TestReparseLookahead(t *testing.T) {
+	newGrammar := func() *llp.Rule {
+		// A word ends before a letter that's followed by a dot
+		word := &llp.Rule{
+			Designation: "word",
+			Kind:        FrWord,
+			Pattern: &llp.Lexed{
+				Kind:      FrWord,
+				MinLen:    1,
+				Lookahead: 1,
+				Fn: func(ix uint, cr llp.Cursor) bool {
+					src := cr.File.Src
+					if !unicode.IsLetter(src[cr.Index]) {
+						return false
+					}
+					next := cr.Index + 1
+					return ix < 1 || next >= uint(len(src)) ||
+						src[next] != '.'
+				},
+			},
+		}
+		return &llp.Rule{
+			Designation: "list",
+			Kind:        kindList,
+			Pattern: &llp.Repeated{Pattern: llp.Either{
+				word,
+				termSpace,
+				&llp.Exact{Expectation: []rune(".")},
+			}},
+		}
+	}
+
+	pr := newParser(t, newGrammar(), nil)
+	pr.Incremental = true
+	tree, err := pr.Parse(newSource("ab. c"))
+	require.NoError(t, err)
+
+	// Replacing the dot changes the word preceding it
+	tree, err = pr.Reparse(tree, []llp.Edit{{Begin: 2, End: 3, Text: "x"}})
+	require.NoError(t, err)
+	expected := mustParse(t, newParser(t, newGrammar(), nil),
+		newSource("abx c"))
+	requireSameTree(t, expected, tree)
+}
+
+func TestReparseErr(t *testing.T) {
+	calls := 0
+	pr := newParser(t, newGroupGrammar(&calls), nil)
+	pr.Incremental = true
+	tree, err := pr.Parse(newSource("a (b c) d"))
+	require.NoError(t, err)
+
+	for name, test := range map[string]struct {
+		Edits    []llp.Edit
+		Expected string
+	}{
+		"Overlapping": {
+			[]llp.Edit{{Begin: 2, End: 5}, {Begin: 4, End: 6}},
+			"overlapping edits",
+		},
+		"Out of range": {
+			[]llp.Edit{{Begin: 2, End: 10}},
+			"edit out of range",
+		},
+		"Reversed": {
+			[]llp.Edit{{Begin: 3, End: 2}},
+			"edit out of range",
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			newTree, err := pr.Reparse(tree, test.Edits)
+			require.Error(t, err)
+			require.Equal(t, test.Expected, err.Error())
+			require.Nil(t, newTree)
+		})
+	}
+
+	// Syntax errors are identical to the ones of a full parse
+	_, err = pr.Reparse(tree, []llp.Edit{{Begin: 6, End: 7}})
+	require.Error(t, err)
+	_, expected := newParser(t, newGroupGrammar(&calls), nil).Parse(
+		newSource("a (b c d"),
+	)
+	require.Equal(t, expected.Error(), err.Error())
+
+	// The tree is still reusable after failed reparses
+	newTree, err := pr.Reparse(tree, []llp.Edit{{Begin: 3, End: 4, Text: "x"}})
+	require.NoError(t, err)
+	require.Equal(t, "a (x c) d", string(newTree.Src()))
+}
+
+func TestReparseChangedConfig(t *testing.T) {
+	calls := 0
+	pr := newParser(t, newGroupGrammar(&calls), nil)
+	pr.Incremental = true
+	src := strings.Repeat("(alpha (beta gamma) delta)\r\n", 100)
+	tree, err := pr.Parse(newSource(src))
+	require.NoError(t, err)
+	fullCalls := calls
+
+	// Matches aren't reused after the line endings changed
+	// since their cursors depend on them
+	pr.LineEndings = llp.LineEndCRLF
+	calls = 0
+	tree, err = pr.Reparse(tree, []llp.Edit{{Begin: 0, End: 0, Text: "x"}})
+	require.NoError(t, err)
+	require.True(t, calls > fullCalls/2, "%d calls", calls)
+	CheckCursor(t, tree.Begin().File, tree.End(), 101, 1)
+}
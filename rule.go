@@ -2,7 +2,19 @@ package parser
 
 // Action represents a callback function that's called when a certain
 // fragment is matched
-type Action func(Fragment) error
+type Action func(ctx *Context, fragment Fragment) error
+
+// UndoAction represents a callback function that's called when
+// the fragment an action was executed for is discarded by backtracking
+type UndoAction func(ctx *Context, fragment Fragment)
+
+// Reducer represents a callback function that computes the semantic value
+// of a matched fragment from the semantic values of its elements
+type Reducer func(
+	ctx *Context,
+	fragment Fragment,
+	values []interface{},
+) (interface{}, error)
 
 // Rule represents a grammatic rule
 type Rule struct {
@@ -10,6 +22,36 @@ type Rule struct {
 	Pattern     Pattern
 	Kind        FragmentKind
 	Action      Action
+
+	// Undo is called in reverse order for every executed action of this
+	// rule when the parser backtracks over its fragment.
+	// Undo is never called when actions are deferred
+	Undo UndoAction
+
+	// Scoped makes the rule open a new symbol scope when it's entered.
+	// The scope is closed before the action of the rule is executed
+	Scoped bool
+
+	// Shape defines how the fragment of the rule is represented
+	// in the fragment of the enclosing rule.
+	// The shape of the main rule of a grammar is ignored
+	Shape Shape
+
+	// Reduce optionally computes the semantic value of the rule
+	// from the values of its elements. The value of a token is the token
+	// itself while rules without a reducer pass the values of their elements
+	// through to the enclosing rule. Values of hidden fragments are dropped.
+	// Reducers are called during parsing even for fragments that are later
+	// discarded by backtracking and should therefore be free of side-effects
+	Reduce Reducer
+
+	// ErrRule is tried at the position of the rule when the rule fails.
+	// When the parse fails at the position of the rule or because of its
+	// failure, the error returned by the action of the matched error-rule
+	// is returned instead of the unexpected-token error. The failed rule
+	// is on the rule stack of the action. The error-grammar of the parser
+	// is only tried when no error-rule of a rule matched
+	ErrRule *Rule
 }
 
 // Container implements the Pattern interface
@@ -4,6 +4,41 @@ package parser
 type scanner struct {
 	Lexer   *lexer
 	Records []Fragment
+
+	// Begin is the position the scanner was created at
+	Begin Cursor
+
+	// Labels holds the labels of the records.
+	// It's nil until the first record is labeled and
+	// may be shorter than Records when the last records aren't labeled
+	Labels []string
+
+	// Values holds the semantic values of the records
+	// and is only maintained when TrackValues is enabled
+	Values      []value
+	TrackValues bool
+
+	// Result holds the semantic values the scanned rule results in
+	Result []value
+
+	// NoTree disables recording fragments
+	// making rules result in tokens without elements
+	NoTree bool
+
+	// Lossless replaces hidden fragments by their tokens
+	Lossless bool
+
+	// Hidden is true when records were hidden by a shape
+	Hidden bool
+
+	// Memo records and reuses rule matches when parsing incrementally
+	Memo *memo
+}
+
+// value represents a semantic value
+type value struct {
+	At    uint
+	Value interface{}
 }
 
 // newScanner creates a new scanner instance
@@ -11,13 +46,20 @@ func newScanner(lexer *lexer) *scanner {
 	if lexer == nil {
 		panic("missing lexer during scanner initialization")
 	}
-	return &scanner{Lexer: lexer}
+	return &scanner{Lexer: lexer, Begin: lexer.cr}
 }
 
 // New creates a new scanner succeeding the original one
 // dropping its record history
 func (sc *scanner) New() *scanner {
-	return &scanner{Lexer: sc.Lexer}
+	return &scanner{
+		Lexer:       sc.Lexer,
+		Begin:       sc.Lexer.cr,
+		TrackValues: sc.TrackValues,
+		NoTree:      sc.NoTree,
+		Lossless:    sc.Lossless,
+		Memo:        sc.Memo,
+	}
 }
 
 // ReadExact advances the scanner by 1 exact token returning either the read
@@ -30,7 +72,8 @@ func (sc *scanner) ReadExact(
 	if err != nil || tk == nil {
 		return
 	}
-	sc.Records = append(sc.Records, tk)
+	sc.record(tk, "")
+	sc.AppendValues(value{At: tk.VBegin.Index, Value: tk})
 	return
 }
 
@@ -44,7 +87,8 @@ func (sc *scanner) ReadUntil(
 	if err != nil || tk == nil {
 		return
 	}
-	sc.Records = append(sc.Records, tk)
+	sc.record(tk, "")
+	sc.AppendValues(value{At: tk.VBegin.Index, Value: tk})
 	return
 }
 
@@ -56,59 +100,158 @@ func (sc *scanner) Append(
 	if fragment == nil {
 		return
 	}
-	if _, ok := pattern.(*Rule); ok {
-		sc.Records = append(sc.Records, fragment)
+	if rule, ok := pattern.(*Rule); ok {
+		sc.record(fragment, "")
+		sc.Shape(len(sc.Records)-1, rule.Shape)
 		return
 	}
 	if termPt := pattern.TerminalPattern(); termPt != nil {
 		if _, ok := termPt.(*Rule); ok {
-			sc.Records = append(sc.Records, fragment)
+			sc.record(fragment, "")
 		}
 	}
 }
 
-// Fragment returns a typed composite fragment
+// AppendValues appends semantic values if values are tracked
+func (sc *scanner) AppendValues(values ...value) {
+	if sc.TrackValues {
+		sc.Values = append(sc.Values, values...)
+	}
+}
+
+// TruncateValues removes all semantic values starting at the given index
+func (sc *scanner) TruncateValues(begin int) {
+	if begin < len(sc.Values) {
+		sc.Values = sc.Values[:begin]
+	}
+}
+
+// Label labels all unlabeled records starting at the given index
+func (sc *scanner) Label(begin int, name string) {
+	if begin >= len(sc.Records) {
+		return
+	}
+	for len(sc.Labels) < len(sc.Records) {
+		sc.Labels = append(sc.Labels, "")
+	}
+	for ix := begin; ix < len(sc.Records); ix++ {
+		if sc.Labels[ix] == "" {
+			sc.Labels[ix] = name
+		}
+	}
+}
+
+// Shape shapes all records starting at the given index.
+// Elements of inlined and collapsed constructs keep their labels and
+// inherit the label of the construct if they're not labeled
+func (sc *scanner) Shape(begin int, shape Shape) {
+	if shape == ShapeKeep || begin >= len(sc.Records) {
+		return
+	}
+
+	// Copy the shaped records since they're overwritten in place
+	records := append([]Fragment(nil), sc.Records[begin:]...)
+	var labels []string
+	if len(sc.Labels) > begin {
+		labels = append([]string(nil), sc.Labels[begin:]...)
+	}
+	sc.Records = sc.Records[:begin]
+	if len(sc.Labels) > begin {
+		sc.Labels = sc.Labels[:begin]
+	}
+	if shape == ShapeHidden {
+		sc.Hidden = true
+		if sc.Lossless {
+			// Keep the tokens of hidden fragments
+			for _, record := range records {
+				sc.Records = appendTokens(sc.Records, record)
+			}
+		}
+		return
+	}
+
+	for ix, record := range records {
+		label := ""
+		if ix < len(labels) {
+			label = labels[ix]
+		}
+		ct, ok := record.(*Construct)
+		if !ok || shape == ShapeCollapse && len(ct.VElements) != 1 {
+			sc.record(record, label)
+			continue
+		}
+		for elIx, el := range ct.VElements {
+			elLabel := ct.Label(elIx)
+			if elLabel == "" {
+				elLabel = label
+			}
+			sc.record(el, elLabel)
+		}
+	}
+}
+
+// record appends a labeled fragment to the records
+func (sc *scanner) record(fragment Fragment, label string) {
+	if sc.NoTree {
+		return
+	}
+	sc.Records = append(sc.Records, fragment)
+	if label != "" {
+		sc.Label(len(sc.Records)-1, label)
+	}
+}
+
+// labels returns the labels of all records
+func (sc *scanner) labels() []string {
+	if sc.Labels == nil {
+		return nil
+	}
+	for len(sc.Labels) < len(sc.Records) {
+		sc.Labels = append(sc.Labels, "")
+	}
+	return sc.Labels
+}
+
+// Fragment returns a typed composite fragment.
+// Constructs span their records unless records were hidden,
+// in which case they span the entire scanned range
 func (sc *scanner) Fragment(kind FragmentKind) Fragment {
-	if len(sc.Records) < 1 {
-		pos := sc.Lexer.cr
-		return &Construct{
-			Token: &Token{
-				VBegin: pos,
-				VEnd:   pos,
-				VKind:  kind,
-			},
-			VElements: nil,
+	if sc.NoTree {
+		return &Token{
+			VBegin: sc.Begin,
+			VEnd:   sc.Lexer.cr,
+			VKind:  kind,
 		}
 	}
-	begin := sc.Records[0].Begin()
-	end := sc.Records[len(sc.Records)-1].End()
-	return &Construct{
+
+	begin, end := sc.Lexer.cr, sc.Lexer.cr
+	if sc.Hidden {
+		begin = sc.Begin
+	} else if len(sc.Records) > 0 {
+		begin = sc.Records[0].Begin()
+		end = sc.Records[len(sc.Records)-1].End()
+	}
+	frag := &Construct{
 		Token: &Token{
 			VBegin: begin,
 			VEnd:   end,
 			VKind:  kind,
 		},
-		VElements: sc.Records,
 	}
+	if len(sc.Records) > 0 {
+		frag.VElements = sc.Records
+		frag.VLabels = sc.labels()
+	}
+	return frag
 }
 
-// Set sets the scanner's lexer position and tidies up the record history
-func (sc *scanner) Set(cursor Cursor) {
+// Reset resets the scanner's lexer position
+// keeping the given number of records and values only
+func (sc *scanner) Reset(cursor Cursor, records, values int) {
 	sc.Lexer.cr = cursor
-	sc.TidyUp()
-}
-
-// TidyUp removes all records after the current position
-func (sc *scanner) TidyUp() (removed int) {
-	pos := sc.Lexer.cr
-	for ix := len(sc.Records) - 1; ix >= 0; ix-- {
-		if sc.Records[ix].Begin().Index < pos.Index {
-			break
-		}
-		removed++
+	sc.Records = sc.Records[:records]
+	if len(sc.Labels) > records {
+		sc.Labels = sc.Labels[:records]
 	}
-
-	// Remove the last n records
-	sc.Records = sc.Records[:len(sc.Records)-removed]
-	return
+	sc.TruncateValues(values)
 }
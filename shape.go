@@ -0,0 +1,69 @@
+package parser
+
+// Shape defines how a matched fragment is represented in the parse-tree
+type Shape uint8
+
+const (
+	// ShapeKeep keeps the fragment as is (default)
+	ShapeKeep Shape = iota
+
+	// ShapeHidden removes the fragment from the parse-tree
+	ShapeHidden
+
+	// ShapeInline replaces a construct by its elements
+	ShapeInline
+
+	// ShapeCollapse replaces a construct consisting of a single element
+	// by this element
+	ShapeCollapse
+)
+
+// String stringifies the shape
+func (sh Shape) String() string {
+	switch sh {
+	case ShapeKeep:
+		return "keep"
+	case ShapeHidden:
+		return "hidden"
+	case ShapeInline:
+		return "inline"
+	case ShapeCollapse:
+		return "collapse"
+	}
+	return "invalid"
+}
+
+// shapeElements returns the given elements shaped by shapeOf recursively
+func shapeElements(
+	elements []Fragment,
+	shapeOf func(Fragment) Shape,
+) []Fragment {
+	if shapeOf == nil {
+		return elements
+	}
+	var shaped []Fragment
+	var add func(frag Fragment)
+	add = func(frag Fragment) {
+		switch shapeOf(frag) {
+		case ShapeHidden:
+			return
+		case ShapeInline:
+			if ct, ok := frag.(*Construct); ok {
+				for _, el := range ct.VElements {
+					add(el)
+				}
+				return
+			}
+		case ShapeCollapse:
+			if ct, ok := frag.(*Construct); ok && len(ct.VElements) == 1 {
+				add(ct.VElements[0])
+				return
+			}
+		}
+		shaped = append(shaped, frag)
+	}
+	for _, el := range elements {
+		add(el)
+	}
+	return shaped
+}
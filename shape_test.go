@@ -0,0 +1,260 @@
+package parser_test
+
+import (
+	"bytes"
+	"testing"
+
+	llp "github.com/romshark/llparser"
+	"github.com/stretchr/testify/require"
+)
+
+func TestShapeHidden(t *testing.T) {
+	pr := newParser(t, &llp.Rule{
+		Designation: "list",
+		Kind:        100,
+		Pattern: llp.Sequence{
+			llp.Shaped{Shape: llp.ShapeHidden, Pattern: termSpace},
+			termLatinWord,
+			&llp.Repeated{
+				Pattern: llp.Sequence{
+					&llp.Rule{
+						Designation: "separator",
+						Shape:       llp.ShapeHidden,
+						Pattern:     termSeparator,
+					},
+					termLatinWord,
+				},
+			},
+			llp.Shaped{Shape: llp.ShapeHidden, Pattern: termSpace},
+		},
+	}, nil)
+
+	src := newSource(" foo,bar ")
+	mainFrag, err := pr.Parse(src)
+	require.NoError(t, err)
+
+	// The construct still spans the hidden fragments
+	checkFrag(t, src, mainFrag, 100, C{1, 1}, C{1, 10}, 2)
+	elems := mainFrag.Elements()
+	checkFrag(t, src, elems[0], FrWord, C{1, 2}, C{1, 5}, 0)
+	checkFrag(t, src, elems[1], FrWord, C{1, 6}, C{1, 9}, 0)
+}
+
+func TestShapeKeepSpan(t *testing.T) {
+	// Unshaped constructs span their elements only
+	pr := newParser(t, &llp.Rule{
+		Designation: "word",
+		Kind:        100,
+		Pattern: llp.Sequence{
+			&llp.Predicate{
+				Fn: func(*llp.Context, llp.Cursor) bool { return true },
+			},
+			termLatinWord,
+			llp.Not{Pattern: termSeparator},
+		},
+	}, nil)
+
+	src := newSource("foo")
+	mainFrag, err := pr.Parse(src)
+	require.NoError(t, err)
+	checkFrag(t, src, mainFrag, 100, C{1, 1}, C{1, 4}, 1)
+}
+
+func TestShapeInline(t *testing.T) {
+	pair := &llp.Rule{
+		Designation: "pair",
+		Kind:        101,
+		Shape:       llp.ShapeInline,
+		Pattern: llp.Sequence{
+			llp.Label{Name: "key", Pattern: termLatinWord},
+			&llp.Exact{Expectation: []rune("=")},
+			termLatinWord,
+		},
+	}
+	pr := newParser(t, &llp.Rule{
+		Designation: "pairs",
+		Kind:        100,
+		Pattern: llp.Sequence{
+			llp.Label{Name: "first", Pattern: pair},
+			termSeparator,
+			llp.Shaped{
+				Shape: llp.ShapeInline,
+				Pattern: &llp.Rule{
+					Designation: "second pair",
+					Pattern:     llp.Label{Name: "second", Pattern: pair},
+				},
+			},
+		},
+	}, nil)
+
+	src := newSource("a=b,c=d")
+	mainFrag, err := pr.Parse(src)
+	require.NoError(t, err)
+	checkFrag(t, src, mainFrag, 100, C{1, 1}, C{1, 8}, 7)
+
+	ct := mainFrag.(*llp.Construct)
+	for ix, expected := range []struct {
+		Kind  FragKind
+		Label string
+	}{
+		{FrWord, "key"},
+		{0, "first"},
+		{FrWord, "first"},
+		{FrSeparator, ""},
+		{FrWord, "key"},
+		{0, "second"},
+		{FrWord, "second"},
+	} {
+		require.Equal(t, expected.Kind, ct.Elements()[ix].Kind())
+		require.Equal(t, expected.Label, ct.Label(ix))
+	}
+	require.Len(t, ct.Children("key"), 2)
+}
+
+func TestShapeCollapse(t *testing.T) {
+	expr := &llp.Rule{
+		Designation: "expression",
+		Kind:        101,
+		Shape:       llp.ShapeCollapse,
+		Pattern: llp.Sequence{
+			termLatinWord,
+			&llp.Repeated{
+				Pattern: llp.Sequence{
+					&llp.Exact{Expectation: []rune("+")},
+					termLatinWord,
+				},
+			},
+		},
+	}
+	pr := newParser(t, &llp.Rule{
+		Designation: "expressions",
+		Kind:        100,
+		Pattern: llp.Sequence{
+			expr,
+			termSeparator,
+			expr,
+		},
+	}, nil)
+
+	src := newSource("a,b+c")
+	mainFrag, err := pr.Parse(src)
+	require.NoError(t, err)
+	checkFrag(t, src, mainFrag, 100, C{1, 1}, C{1, 6}, 3)
+
+	elems := mainFrag.Elements()
+
+	// Collapsed into the only element
+	checkFrag(t, src, elems[0], FrWord, C{1, 1}, C{1, 2}, 0)
+	checkFrag(t, src, elems[1], FrSeparator, C{1, 2}, C{1, 3}, 0)
+
+	// Kept since there's more than one element
+	checkFrag(t, src, elems[2], 101, C{1, 3}, C{1, 6}, 3)
+}
+
+func TestShapeBacktracking(t *testing.T) {
+	inlined := func(pattern llp.Pattern) *llp.Rule {
+		return &llp.Rule{Shape: llp.ShapeInline, Pattern: pattern}
+	}
+	pr := newParser(t, &llp.Rule{
+		Designation: "main",
+		Kind:        100,
+		Pattern: llp.Either{
+			inlined(llp.Sequence{
+				inlined(llp.Sequence{testR_foo, testR_bar}),
+				testR_foo,
+			}),
+			inlined(llp.Sequence{testR_foo, testR_bar, testR_bar}),
+		},
+	}, nil)
+
+	src := newSource("foobarbar")
+	mainFrag, err := pr.Parse(src)
+	require.NoError(t, err)
+	checkFrag(t, src, mainFrag, 100, C{1, 1}, C{1, 10}, 3)
+
+	elems := mainFrag.Elements()
+	checkFrag(t, src, elems[0], FrFoo, C{1, 1}, C{1, 4}, 1)
+	checkFrag(t, src, elems[1], FrBar, C{1, 4}, C{1, 7}, 1)
+	checkFrag(t, src, elems[2], FrBar, C{1, 7}, C{1, 10}, 1)
+}
+
+func TestShapeDebug(t *testing.T) {
+	pr := newParser(t, &llp.Rule{
+		Designation: "main",
+		Kind:        100,
+		Pattern: llp.Sequence{
+			testR_foo,
+			llp.Shaped{Shape: llp.ShapeHidden, Pattern: termSpace},
+			testR_bar,
+		},
+	}, nil)
+
+	src := newSource("foo bar")
+	profile, mainFrag, err := pr.Debug(src)
+	require.NoError(t, err)
+	checkFrag(t, src, mainFrag, 100, C{1, 1}, C{1, 8}, 2)
+
+	checkExpectations(t, profile,
+		E{"test.txt:1:1", "rule (main)", 0, true},
+		E{"test.txt:1:1", "sequence <- rule (keyword foo), " +
+			"shaped (hidden) <- lexed (space), " +
+			"rule (keyword bar)", 1, true},
+		E{"test.txt:1:1", "rule (keyword foo)", 2, true},
+		E{"test.txt:1:1", "exact (0)", 3, true},
+		E{"test.txt:1:4", "shaped (hidden) <- lexed (space)", 2, true},
+		E{"test.txt:1:4", "lexed (space)", 3, true},
+		E{"test.txt:1:5", "rule (keyword bar)", 2, true},
+		E{"test.txt:1:5", "exact (0)", 3, true},
+	)
+}
+
+func TestShapePrintFragment(t *testing.T) {
+	pr := newParser(t, &llp.Rule{
+		Kind: 100,
+		Pattern: llp.Sequence{
+			&llp.Rule{
+				Kind:    101,
+				Pattern: &llp.Exact{Kind: 102, Expectation: []rune("a")},
+			},
+			&llp.Exact{Kind: 103, Expectation: []rune(" ")},
+			&llp.Rule{
+				Kind: 104,
+				Pattern: llp.Sequence{
+					&llp.Exact{Kind: 105, Expectation: []rune("b")},
+					&llp.Exact{Kind: 106, Expectation: []rune("c")},
+				},
+			},
+		},
+	}, nil)
+
+	mainFrag, err := pr.Parse(newSource("a bc"))
+	require.NoError(t, err)
+
+	bf := &bytes.Buffer{}
+	_, err = llp.PrintFragment(mainFrag, llp.FragPrintOptions{
+		Out: bf,
+		Shape: func(frag llp.Fragment) llp.Shape {
+			switch frag.Kind() {
+			case 101:
+				return llp.ShapeCollapse
+			case 103:
+				return llp.ShapeHidden
+			case 104:
+				return llp.ShapeInline
+			}
+			return llp.ShapeKeep
+		},
+	})
+	require.NoError(t, err)
+	require.Equal(
+		t,
+		"100 (test.txt: 1:1-1:5 'a bc') {"+
+			" 102 (test.txt: 1:1-1:2 'a')"+
+			" 105 (test.txt: 1:3-1:4 'b')"+
+			" 106 (test.txt: 1:4-1:5 'c') }",
+		bf.String(),
+	)
+
+	// The parse-tree itself isn't affected
+	require.Len(t, mainFrag.Elements(), 3)
+}
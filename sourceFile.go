@@ -0,0 +1,176 @@
+package parser
+
+import (
+	"fmt"
+	"sort"
+)
+
+// DefaultTabWidth defines the tab width used by SourceFile.DisplayColumn
+// when SourceFile.TabWidth is 0
+const DefaultTabWidth = 4
+
+// lineIndex indexes the first runes of all lines of a file
+// recognizing the given line endings
+type lineIndex struct {
+	lines []uint
+	ends  LineEndings
+}
+
+// lineIndex returns the indexes of the first runes of all lines
+// building the index on first access. Concurrent first accesses
+// may build the index multiple times
+func (f *SourceFile) lineIndex() []uint {
+	if index, ok := f.lines.Load().(*lineIndex); ok &&
+		index.ends == f.LineEndings {
+		return index.lines
+	}
+	index := &lineIndex{lines: []uint{0}, ends: f.LineEndings}
+	for ix := range f.Src {
+		if f.LineEndings.endsLine(f.Src, uint(ix)) {
+			index.lines = append(index.lines, uint(ix+1))
+		}
+	}
+	f.lines.Store(index)
+	return index.lines
+}
+
+// cursor returns the cursor at the given index which must be in range
+func (f *SourceFile) cursor(index uint) Cursor {
+	lines := f.lineIndex()
+	line := sort.Search(len(lines), func(ix int) bool {
+		return lines[ix] > index
+	})
+	return Cursor{
+		Index:  index,
+		Column: index - lines[line-1] + 1,
+		Line:   uint(line),
+		File:   f,
+	}
+}
+
+// lineRange returns the indexes of the first rune of the given line
+// and of its line-break or the end of the file
+func (f *SourceFile) lineRange(line uint) (begin, end uint, err error) {
+	lines := f.lineIndex()
+	if line < 1 || line > uint(len(lines)) {
+		return 0, 0, fmt.Errorf("line %d out of range", line)
+	}
+	begin, end = lines[line-1], uint(len(f.Src))
+	if line < uint(len(lines)) {
+		// Exclude the line-break
+		end = lines[line] - 1
+		if end > begin && f.LineEndings.breakLen(f.Src, end-1) == 2 {
+			end--
+		}
+	}
+	return begin, end, nil
+}
+
+// lineOf returns the beginning of the line of the given offset
+func (f *SourceFile) lineOf(offset uint) (Cursor, uint, error) {
+	if offset > uint(len(f.Src)) {
+		return Cursor{}, 0, fmt.Errorf("offset %d out of range", offset)
+	}
+	cr := f.cursor(offset)
+	return cr, offset - (cr.Column - 1), nil
+}
+
+// LineCount returns the number of lines
+func (f *SourceFile) LineCount() uint { return uint(len(f.lineIndex())) }
+
+// CursorAt returns the cursor at the given offset
+func (f *SourceFile) CursorAt(offset uint) (Cursor, error) {
+	cr, _, err := f.lineOf(offset)
+	return cr, err
+}
+
+// Offset returns the offset of the given line and column
+func (f *SourceFile) Offset(line, column uint) (uint, error) {
+	begin, end, err := f.lineRange(line)
+	if err != nil {
+		return 0, err
+	}
+	if column < 1 || begin+column-1 > end {
+		return 0, fmt.Errorf("column %d out of range of line %d", column, line)
+	}
+	return begin + column - 1, nil
+}
+
+// Line returns the source code of the given line
+// excluding its line-break
+func (f *SourceFile) Line(line uint) ([]rune, error) {
+	begin, end, err := f.lineRange(line)
+	if err != nil {
+		return nil, err
+	}
+	return f.Src[begin:end], nil
+}
+
+// DisplayColumn returns the column of the given offset
+// as displayed with tabs expanded to the next tab stop
+func (f *SourceFile) DisplayColumn(offset uint) (uint, error) {
+	_, begin, err := f.lineOf(offset)
+	if err != nil {
+		return 0, err
+	}
+	width := f.TabWidth
+	if width < 1 {
+		width = DefaultTabWidth
+	}
+	column := uint(0)
+	for _, rn := range f.Src[begin:offset] {
+		if rn == '\t' {
+			column += width - column%width
+			continue
+		}
+		column++
+	}
+	return column + 1, nil
+}
+
+// utf16Len returns the number of UTF-16 code units encoding the rune
+func utf16Len(rn rune) uint {
+	if rn >= 0x10000 && rn <= 0x10FFFF {
+		return 2
+	}
+	return 1
+}
+
+// UTF16Column returns the column of the given offset in UTF-16 code units
+// as used by the Language Server Protocol. Like all columns it's 1-based
+// while LSP positions are 0-based
+func (f *SourceFile) UTF16Column(offset uint) (uint, error) {
+	_, begin, err := f.lineOf(offset)
+	if err != nil {
+		return 0, err
+	}
+	column := uint(1)
+	for _, rn := range f.Src[begin:offset] {
+		column += utf16Len(rn)
+	}
+	return column, nil
+}
+
+// UTF16Offset returns the offset of the given line and column in UTF-16
+// code units (see UTF16Column). A column pointing into a surrogate pair
+// resolves to the offset of the encoded rune
+func (f *SourceFile) UTF16Offset(line, column uint) (uint, error) {
+	begin, end, err := f.lineRange(line)
+	if err != nil {
+		return 0, err
+	}
+	if column < 1 {
+		return 0, fmt.Errorf("column %d out of range of line %d", column, line)
+	}
+	units := uint(1)
+	for ix := begin; ix < end; ix++ {
+		units += utf16Len(f.Src[ix])
+		if units > column {
+			return ix, nil
+		}
+	}
+	if units < column {
+		return 0, fmt.Errorf("column %d out of range of line %d", column, line)
+	}
+	return end, nil
+}
@@ -0,0 +1,146 @@
+package parser_test
+
+import (
+	"testing"
+
+	llp "github.com/romshark/llparser"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSourceFileLines(t *testing.T) {
+	src := newSource("ab\n\tc\n\nd")
+	require.Equal(t, uint(4), src.LineCount())
+
+	for offset, expected := range []C{
+		{1, 1}, {1, 2}, {1, 3},
+		{2, 1}, {2, 2}, {2, 3},
+		{3, 1},
+		{4, 1}, {4, 2},
+	} {
+		cr, err := src.CursorAt(uint(offset))
+		require.NoError(t, err)
+		CheckCursor(t, src, cr, expected.line, expected.column)
+		require.Equal(t, uint(offset), cr.Index)
+
+		back, err := src.Offset(expected.line, expected.column)
+		require.NoError(t, err)
+		require.Equal(t, uint(offset), back)
+	}
+
+	for line, expected := range []string{"ab", "\tc", "", "d"} {
+		text, err := src.Line(uint(line + 1))
+		require.NoError(t, err)
+		require.Equal(t, expected, string(text))
+	}
+}
+
+// TestSourceFileCopy tests copying files whose lines have been accessed
+func TestSourceFileCopy(t *testing.T) {
+	src := newSource("a\rb\nc")
+	require.Equal(t, uint(2), src.LineCount())
+
+	cp := *src
+	cp.LineEndings = llp.LineEndLF | llp.LineEndCR
+	require.Equal(t, uint(3), cp.LineCount())
+	require.Equal(t, uint(2), src.LineCount())
+
+	cr, err := cp.CursorAt(2)
+	require.NoError(t, err)
+	CheckCursor(t, &cp, cr, 2, 1)
+}
+
+func TestSourceFileDisplayColumn(t *testing.T) {
+	src := newSource("a\tb\n\t\tc")
+	for offset, expected := range map[uint]uint{
+		0: 1, 1: 2, 2: 5, 4: 1, 5: 5, 6: 9,
+	} {
+		column, err := src.DisplayColumn(offset)
+		require.NoError(t, err)
+		require.Equal(t, expected, column, "offset %d", offset)
+	}
+
+	src.TabWidth = 8
+	column, err := src.DisplayColumn(2)
+	require.NoError(t, err)
+	require.Equal(t, uint(9), column)
+}
+
+func TestSourceFileUTF16(t *testing.T) {
+	// The emoji is encoded as a surrogate pair
+	src := newSource("a😀b\nc")
+	for offset, expected := range map[uint]uint{
+		0: 1, 1: 2, 2: 4, 3: 5, 4: 1, 5: 2,
+	} {
+		column, err := src.UTF16Column(offset)
+		require.NoError(t, err)
+		require.Equal(t, expected, column, "offset %d", offset)
+
+		cr, err := src.CursorAt(offset)
+		require.NoError(t, err)
+		back, err := src.UTF16Offset(cr.Line, column)
+		require.NoError(t, err)
+		require.Equal(t, offset, back)
+	}
+
+	// Columns inside the surrogate pair resolve to the emoji
+	offset, err := src.UTF16Offset(1, 3)
+	require.NoError(t, err)
+	require.Equal(t, uint(1), offset)
+}
+
+func TestSourceFileErr(t *testing.T) {
+	src := newSource("ab\nc")
+	for name, test := range map[string]struct {
+		Fn       func(*llp.SourceFile) error
+		Expected string
+	}{
+		"Offset": {
+			func(f *llp.SourceFile) error {
+				_, err := f.CursorAt(5)
+				return err
+			},
+			"offset 5 out of range",
+		},
+		"Line": {
+			func(f *llp.SourceFile) error {
+				_, err := f.Line(3)
+				return err
+			},
+			"line 3 out of range",
+		},
+		"Line zero": {
+			func(f *llp.SourceFile) error {
+				_, err := f.Offset(0, 1)
+				return err
+			},
+			"line 0 out of range",
+		},
+		"Column": {
+			func(f *llp.SourceFile) error {
+				_, err := f.Offset(1, 4)
+				return err
+			},
+			"column 4 out of range of line 1",
+		},
+		"UTF-16 column": {
+			func(f *llp.SourceFile) error {
+				_, err := f.UTF16Offset(2, 3)
+				return err
+			},
+			"column 3 out of range of line 2",
+		},
+		"Display column": {
+			func(f *llp.SourceFile) error {
+				_, err := f.DisplayColumn(9)
+				return err
+			},
+			"offset 9 out of range",
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			err := test.Fn(src)
+			require.Error(t, err)
+			require.Equal(t, test.Expected, err.Error())
+		})
+	}
+}
@@ -0,0 +1,181 @@
+package parser
+
+import (
+	"sort"
+	"unicode"
+)
+
+// maxSuggestions defines the maximum number of suggestions of an error
+const maxSuggestions = 3
+
+// Suggestion represents a suggested replacement of the source code
+// in the range between Begin and End
+type Suggestion struct {
+	Begin Cursor
+	End   Cursor
+	Text  string
+}
+
+// firstExacts collects the exact terminals that can be matched first
+// by the pattern. Returns true if the pattern can match without
+// consuming any source code
+func firstExacts(
+	pattern Pattern,
+	exacts []*Exact,
+	visited map[*Rule]bool,
+) ([]*Exact, bool) {
+	switch pt := pattern.(type) {
+	case *Exact:
+		return append(exacts, pt), false
+	case *Rule:
+		if visited[pt] {
+			return exacts, false
+		}
+		visited[pt] = true
+		return firstExacts(pt.Pattern, exacts, visited)
+	case Either:
+		optional := false
+		for _, option := range pt {
+			var opt bool
+			exacts, opt = firstExacts(option, exacts, visited)
+			optional = optional || opt
+		}
+		return exacts, optional
+	case Sequence:
+		for _, el := range pt {
+			var opt bool
+			if exacts, opt = firstExacts(el, exacts, visited); !opt {
+				return exacts, false
+			}
+		}
+		return exacts, true
+	case *Repeated:
+		exacts, opt := firstExacts(pt.Pattern, exacts, visited)
+		return exacts, opt || pt.Min == 0
+	case Label:
+		return firstExacts(pt.Pattern, exacts, visited)
+	case Shaped:
+		return firstExacts(pt.Pattern, exacts, visited)
+	case Not, *Predicate, Cut:
+		return exacts, true
+	}
+	return exacts, false
+}
+
+func isWordRune(rn rune) bool {
+	return rn == '_' || unicode.IsLetter(rn) || unicode.IsDigit(rn)
+}
+
+// editDistance returns the case-insensitive optimal string alignment
+// distance counting insertions, deletions, substitutions and
+// transpositions of adjacent runes
+func editDistance(a, b []rune) int {
+	prev2 := make([]int, len(b)+1)
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if unicode.ToLower(a[i-1]) == unicode.ToLower(b[j-1]) {
+				cost = 0
+			}
+			cur[j] = minInt(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+			if i > 1 && j > 1 &&
+				unicode.ToLower(a[i-1]) == unicode.ToLower(b[j-2]) &&
+				unicode.ToLower(a[i-2]) == unicode.ToLower(b[j-1]) {
+				cur[j] = minInt(cur[j], prev2[j-2]+1)
+			}
+		}
+		prev2, prev, cur = prev, cur, prev2
+	}
+	return prev[len(b)]
+}
+
+func minInt(values ...int) int {
+	m := values[0]
+	for _, v := range values[1:] {
+		if v < m {
+			m = v
+		}
+	}
+	return m
+}
+
+// suggest returns the exact expectations of the error similar to the
+// source code at the position of the error ordered by similarity
+func suggest(err *ErrUnexpectedToken) []Suggestion {
+	file := err.At.File
+	if err.Expected == nil || file == nil ||
+		err.At.Index >= uint(len(file.Src)) {
+		return nil
+	}
+	pattern := err.expectedAt
+	if pattern == nil {
+		pattern = err.Expected
+	}
+	exacts, _ := firstExacts(pattern, nil, map[*Rule]bool{})
+
+	// Offending words are compared entirely
+	// while other runes are compared to the length of the expectation
+	begin := err.At.Index
+	wordEnd := begin
+	for wordEnd < uint(len(file.Src)) && isWordRune(file.Src[wordEnd]) {
+		wordEnd++
+	}
+
+	type candidate struct {
+		suggestion Suggestion
+		distance   int
+	}
+	var candidates []candidate
+	seen := map[string]bool{}
+	for _, exact := range exacts {
+		expected := exact.Expectation
+		if seen[string(expected)] {
+			continue
+		}
+		seen[string(expected)] = true
+
+		end := wordEnd
+		if end == begin || !isWordRune(expected[0]) {
+			end = begin + uint(len(expected))
+			if end > uint(len(file.Src)) {
+				end = uint(len(file.Src))
+			}
+		}
+		actual := file.Src[begin:end]
+		if string(actual) == string(expected) {
+			continue
+		}
+		// Tolerate a typo per 3 runes and at least one in words
+		tolerated := len(expected) / 3
+		if tolerated < 1 && isWordRune(expected[0]) && len(expected) > 1 {
+			tolerated = 1
+		}
+		distance := editDistance(actual, expected)
+		if distance > tolerated {
+			continue
+		}
+		candidates = append(candidates, candidate{
+			suggestion: Suggestion{
+				Begin: err.At,
+				End:   file.cursor(end),
+				Text:  string(expected),
+			},
+			distance: distance,
+		})
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].distance < candidates[j].distance
+	})
+
+	var suggestions []Suggestion
+	for ix := 0; ix < len(candidates) && ix < maxSuggestions; ix++ {
+		suggestions = append(suggestions, candidates[ix].suggestion)
+	}
+	return suggestions
+}
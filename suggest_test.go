@@ -0,0 +1,115 @@
+package parser_test
+
+import (
+	"testing"
+
+	llp "github.com/romshark/llparser"
+	"github.com/stretchr/testify/require"
+)
+
+// newKeywordGrammar creates a grammar of a single keyword
+// optionally followed by a semicolon
+func newKeywordGrammar() *llp.Rule {
+	keyword := func(kw string) *llp.Exact {
+		return &llp.Exact{Expectation: []rune(kw)}
+	}
+	return &llp.Rule{
+		Designation: "value",
+		Pattern: llp.Sequence{
+			&llp.Rule{
+				Designation: "keyword",
+				Pattern: llp.Either{
+					keyword("true"),
+					keyword("false"),
+					keyword("null"),
+					keyword("=>"),
+				},
+			},
+			&llp.Repeated{Max: 1, Pattern: keyword(";")},
+		},
+	}
+}
+
+func TestSuggestions(t *testing.T) {
+	for name, test := range map[string]struct {
+		Src      string
+		Expected []string
+		End      uint
+	}{
+		"Transposition": {"ture;", []string{"true"}, 4},
+		"Case":          {"FALSE", []string{"false"}, 5},
+		"Case and transposition": {
+			"Fasle;", []string{"false"}, 5,
+		},
+		"Deletion":  {"nul", []string{"null"}, 3},
+		"Insertion": {"trrue", []string{"true"}, 5},
+		"Signs":     {"=<", nil, 0},
+		"Unrelated": {"xyz", nil, 0},
+	} {
+		t.Run(name, func(t *testing.T) {
+			pr := newParser(t, newKeywordGrammar(), nil)
+			_, err := pr.Parse(newSource(test.Src))
+			require.Error(t, err)
+			require.IsType(t, &llp.ErrUnexpectedToken{}, err)
+
+			suggestions := err.(*llp.ErrUnexpectedToken).Suggestions
+			require.Len(t, suggestions, len(test.Expected))
+			for ix, expected := range test.Expected {
+				require.Equal(t, expected, suggestions[ix].Text)
+				require.Equal(t, uint(0), suggestions[ix].Begin.Index)
+				require.Equal(t, test.End, suggestions[ix].End.Index)
+			}
+		})
+	}
+}
+
+func TestSuggestionsSequence(t *testing.T) {
+	// Suggestions are made for the terminals expected
+	// at the position of the mismatch inside the rule
+	decl := &llp.Rule{
+		Designation: "decl",
+		Pattern: llp.Sequence{
+			&llp.Exact{Expectation: []rune("func")},
+			&llp.Exact{Expectation: []rune(" ")},
+			llp.Either{
+				&llp.Exact{Expectation: []rune("main")},
+				&llp.Exact{Expectation: []rune("init")},
+			},
+		},
+	}
+	pr := newParser(t, &llp.Rule{
+		Designation: "file",
+		Pattern:     &llp.Repeated{Min: 1, Pattern: decl},
+	}, nil)
+
+	for src, expected := range map[string]string{
+		"func mian": "unexpected token, expected {decl} at test.txt:1:6, " +
+			"did you mean 'main'?",
+		"func int": "unexpected token, expected {decl} at test.txt:1:6, " +
+			"did you mean 'init'?",
+		"func fund": "unexpected token, expected {decl} at test.txt:1:6",
+	} {
+		_, err := pr.Parse(newSource(src))
+		require.Error(t, err)
+		require.Equal(t, expected, err.Error(), src)
+	}
+}
+
+func TestSuggestionsDiagnostic(t *testing.T) {
+	pr := newParser(t, newKeywordGrammar(), nil)
+	_, err := pr.Parse(newSource("ture;"))
+	require.Error(t, err)
+	require.Equal(
+		t,
+		"unexpected token, expected {keyword} at test.txt:1:1, "+
+			"did you mean 'true'?",
+		err.Error(),
+	)
+
+	diag := llp.ToDiagnostic(err)
+	require.Equal(t, uint(4), diag.End.Index)
+	require.Equal(t, []llp.Fix{{
+		Message: "did you mean 'true'?",
+		Edits:   []llp.Edit{{Begin: 0, End: 4, Text: "true"}},
+	}}, diag.Fixes)
+}
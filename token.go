@@ -36,16 +36,18 @@ func (tk *Token) Src() []rune {
 // Elements always returns nil for terminal fragments
 func (tk *Token) Elements() []Fragment { return nil }
 
-// String stringifies the token
-func (tk *Token) String() string {
+// String stringifies the token using the kind names of DefaultKinds
+func (tk *Token) String() string { return tk.string(DefaultKinds) }
+
+func (tk *Token) string(kinds *KindRegistry) string {
 	fileName := "<unknown>"
 	if tk.VBegin.File != nil {
 		fileName = tk.VBegin.File.Name
 	}
 
 	return fmt.Sprintf(
-		"%d (%s: %d:%d-%d:%d '%s')",
-		tk.VKind,
+		"%s (%s: %d:%d-%d:%d '%s')",
+		kinds.nameOf(tk.VKind),
 		fileName,
 		tk.VBegin.Line, tk.VBegin.Column,
 		tk.VEnd.Line, tk.VEnd.Column,
@@ -0,0 +1,127 @@
+package parser_test
+
+import (
+	"testing"
+
+	llp "github.com/romshark/llparser"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTolerant(t *testing.T) {
+	pr := newParser(t, newCallGrammar(), nil)
+	pr.Kinds = newKinds(t)
+
+	for _, tt := range []struct {
+		name  string
+		src   string
+		tree  string
+		diags []string
+	}{
+		{
+			name: "Valid",
+			src:  "f(x);g();",
+			tree: `(file (call (word "f") (separator "(") (word "x") ` +
+				`(separator ")") (separator ";")) ` +
+				`(call (word "g") (separator "(") (separator ")") ` +
+				`(separator ";")))`,
+		},
+		{
+			name: "Missing",
+			src:  "f(x;g();",
+			tree: `(file (call (word "f") (separator "(") (word "x") ` +
+				`(missing "") (separator ";")) ` +
+				`(call (word "g") (separator "(") (separator ")") ` +
+				`(separator ";")))`,
+			diags: []string{"missing 3-3: missing ')'"},
+		},
+		{
+			name: "MissingAtEOF",
+			src:  "f(x",
+			tree: `(file (call (word "f") (separator "(") (word "x") ` +
+				`(missing "") (missing "")))`,
+			// The diagnostic of the missing ';' is suppressed
+			diags: []string{"missing 3-3: missing ')'"},
+		},
+		{
+			name: "Skipped",
+			src:  "f();??g();",
+			tree: `(file (call (word "f") (separator "(") (separator ")") ` +
+				`(separator ";")) ` +
+				`(call (error "??") (word "g") (separator "(") ` +
+				`(separator ")") (separator ";")))`,
+			diags: []string{"unexpected-token 4-6: unexpected token"},
+		},
+		{
+			name: "SkippedInside",
+			src:  "f(x y);",
+			tree: `(file (call (word "f") (separator "(") (word "x") ` +
+				`(error " y") (separator ")") (separator ";")))`,
+			diags: []string{"unexpected-token 3-5: unexpected token"},
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			tree, diags, _ := parseTolerant(t, pr, tt.src, llp.ParseOptions{})
+			require.Equal(t, tt.tree, tree)
+			require.Equal(t, tt.diags, diags)
+		})
+	}
+}
+
+func TestTolerantUnrecoverable(t *testing.T) {
+	pr := newParser(t, &llp.Rule{
+		Kind:    FrFoo,
+		Pattern: &llp.Exact{Kind: FrWord, Expectation: []rune("foo")},
+	}, nil)
+
+	tree, diags, _ := parseTolerant(t, pr, "bar", llp.ParseOptions{})
+	require.Equal(t, `(4 (error "bar"))`, tree)
+	require.Equal(t, []string{"unexpected-token 0-3: unexpected token"}, diags)
+
+	tree, diags, _ = parseTolerant(t, pr, "foobar", llp.ParseOptions{})
+	require.Equal(t, `(4 (3 "foo") (error "bar"))`, tree)
+	require.Equal(t, []string{"unexpected-token 3-6: unexpected token"}, diags)
+}
+
+func TestTolerantErrRule(t *testing.T) {
+	item := newItemRule()
+	pr := newParser(t, &llp.Rule{
+		Designation: "list",
+		Pattern: llp.Sequence{
+			item,
+			&llp.Repeated{Pattern: llp.Sequence{termSeparator, item}},
+		},
+	}, nil)
+
+	_, diags, _ := parseTolerant(t, pr, "foo,baz,bar", llp.ParseOptions{})
+	require.Equal(t, []string{`action 4-7: unknown item "baz"`}, diags)
+}
+
+func TestTolerantActions(t *testing.T) {
+	var calls []string
+	grammar := newCallGrammar()
+	call := grammar.Pattern.(*llp.Repeated).Pattern.(*llp.Rule)
+	call.Action = func(_ *llp.Context, frag llp.Fragment) error {
+		calls = append(calls, string(frag.Src()))
+		return nil
+	}
+	pr := newParser(t, grammar, nil)
+	pr.DeferActions = true
+
+	_, diags, _ := parseTolerant(t, pr, "f(x;g();", llp.ParseOptions{})
+	require.Len(t, diags, 1)
+	require.Equal(t, []string{"f(x;", "g();"}, calls)
+}
+
+func TestTolerantMaxRecoveries(t *testing.T) {
+	pr := newParser(t, newCallGrammar(), nil)
+	pr.Kinds = newKinds(t)
+
+	// After recovering once the rest is covered by an error token
+	_, diags, _ := parseTolerant(t, pr, "f(x;g(y;h(z;", llp.ParseOptions{
+		MaxRecoveries: 1,
+	})
+	require.Equal(t, []string{
+		"missing 3-3: missing ')'",
+		"unexpected-token 4-12: unexpected token",
+	}, diags)
+}
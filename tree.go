@@ -0,0 +1,103 @@
+package parser
+
+// treeNode represents the position of a fragment in a tree
+type treeNode struct {
+	parent Fragment
+	index  int
+	depth  int
+}
+
+// Tree represents an indexed view of a parse-tree providing
+// constant-time navigation to the parent and siblings of a fragment.
+// The tree must not be modified after the view is built
+type Tree struct {
+	root  Fragment
+	nodes map[Fragment]treeNode
+}
+
+// NewTree builds an indexed view of the tree of the given root fragment
+func NewTree(root Fragment) *Tree {
+	tr := &Tree{
+		root:  root,
+		nodes: map[Fragment]treeNode{root: {index: -1}},
+	}
+	var index func(frag Fragment, depth int)
+	index = func(frag Fragment, depth int) {
+		for ix, el := range frag.Elements() {
+			tr.nodes[el] = treeNode{parent: frag, index: ix, depth: depth}
+			index(el, depth+1)
+		}
+	}
+	index(root, 1)
+	return tr
+}
+
+// Root returns the root fragment of the tree
+func (tr *Tree) Root() Fragment { return tr.root }
+
+// Contains returns true if the fragment is part of the tree
+func (tr *Tree) Contains(fragment Fragment) bool {
+	_, ok := tr.nodes[fragment]
+	return ok
+}
+
+// Parent returns the parent of the fragment or nil
+// for the root and fragments that aren't part of the tree
+func (tr *Tree) Parent(fragment Fragment) Fragment {
+	return tr.nodes[fragment].parent
+}
+
+// Index returns the index of the fragment among its siblings or -1
+// for the root and fragments that aren't part of the tree
+func (tr *Tree) Index(fragment Fragment) int {
+	node, ok := tr.nodes[fragment]
+	if !ok {
+		return -1
+	}
+	return node.index
+}
+
+// Depth returns the depth of the fragment where the root is at depth 0
+// or -1 if the fragment isn't part of the tree
+func (tr *Tree) Depth(fragment Fragment) int {
+	node, ok := tr.nodes[fragment]
+	if !ok {
+		return -1
+	}
+	return node.depth
+}
+
+// sibling returns the sibling at the given offset or nil if there's none
+func (tr *Tree) sibling(fragment Fragment, offset int) Fragment {
+	node, ok := tr.nodes[fragment]
+	if !ok || node.parent == nil {
+		return nil
+	}
+	siblings := node.parent.Elements()
+	ix := node.index + offset
+	if ix < 0 || ix >= len(siblings) {
+		return nil
+	}
+	return siblings[ix]
+}
+
+// NextSibling returns the following sibling of the fragment or nil
+func (tr *Tree) NextSibling(fragment Fragment) Fragment {
+	return tr.sibling(fragment, 1)
+}
+
+// PrevSibling returns the preceding sibling of the fragment or nil
+func (tr *Tree) PrevSibling(fragment Fragment) Fragment {
+	return tr.sibling(fragment, -1)
+}
+
+// Ancestors returns the ancestors of the fragment
+// starting with its parent and ending with the root
+func (tr *Tree) Ancestors(fragment Fragment) []Fragment {
+	var ancestors []Fragment
+	for parent := tr.Parent(fragment); parent != nil; {
+		ancestors = append(ancestors, parent)
+		parent = tr.Parent(parent)
+	}
+	return ancestors
+}
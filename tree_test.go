@@ -0,0 +1,58 @@
+package parser_test
+
+import (
+	"testing"
+
+	llp "github.com/romshark/llparser"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTree(t *testing.T) {
+	pr := newParser(t, newListGrammar(), nil)
+	src := newSource("a,(b,(c)),d")
+	mainFrag := mustParse(t, pr, src)
+	tree := llp.NewTree(mainFrag)
+	require.Equal(t, mainFrag, tree.Root())
+
+	c := llp.FragmentAt(mainFrag, llp.Cursor{Index: 6, File: src})
+	require.True(t, tree.Contains(c))
+	require.Equal(t, 3, tree.Depth(c))
+	require.Equal(t, 1, tree.Index(c))
+
+	parent := tree.Parent(c)
+	require.Equal(t, "(c)", string(parent.Src()))
+	require.Equal(t, "(", string(tree.PrevSibling(c).Src()))
+	require.Equal(t, ")", string(tree.NextSibling(c).Src()))
+	require.Equal(
+		t,
+		[]string{"(c)", "(b,(c))", "a,(b,(c)),d"},
+		fragSrc(tree.Ancestors(c)),
+	)
+
+	t.Run("FirstAndLast", func(t *testing.T) {
+		elems := mainFrag.Elements()
+		require.Nil(t, tree.PrevSibling(elems[0]))
+		require.Equal(t, elems[1], tree.NextSibling(elems[0]))
+		require.Nil(t, tree.NextSibling(elems[len(elems)-1]))
+		require.Equal(t, len(elems)-1, tree.Index(elems[len(elems)-1]))
+	})
+
+	t.Run("Root", func(t *testing.T) {
+		require.True(t, tree.Contains(mainFrag))
+		require.Nil(t, tree.Parent(mainFrag))
+		require.Equal(t, -1, tree.Index(mainFrag))
+		require.Equal(t, 0, tree.Depth(mainFrag))
+		require.Nil(t, tree.NextSibling(mainFrag))
+		require.Nil(t, tree.PrevSibling(mainFrag))
+		require.Len(t, tree.Ancestors(mainFrag), 0)
+	})
+
+	t.Run("Foreign", func(t *testing.T) {
+		foreign := mustParse(t, pr, newSource("a"))
+		require.False(t, tree.Contains(foreign))
+		require.Nil(t, tree.Parent(foreign))
+		require.Equal(t, -1, tree.Index(foreign))
+		require.Equal(t, -1, tree.Depth(foreign))
+		require.Nil(t, tree.NextSibling(foreign))
+	})
+}
@@ -0,0 +1,349 @@
+package parser
+
+import (
+	"encoding"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+var (
+	typeCursor          = reflect.TypeOf(Cursor{})
+	typeFragment        = reflect.TypeOf((*Fragment)(nil)).Elem()
+	typeTextUnmarshaler = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+// Unmarshaler unmarshals parse-trees into Go values.
+//
+// Struct fields are mapped to the element fragments of a construct
+// using the "llp" struct tag:
+//
+//	Name  string   `llp:"name"`           // element labeled "name"
+//	Items []Item   `llp:"kind=5"`         // all elements of kind 5
+//	Alias *string  `llp:"alias"`          // optional element
+//	Port  int      `llp:"port,optional"`  // optional element
+//	Text  string   `llp:"."`              // the construct itself
+//
+// Non-slice fields require exactly one matching element unless they're
+// pointers or marked optional. Tokens are converted to strings, byte and
+// rune slices, numbers and booleans by their source text, types implementing
+// encoding.TextUnmarshaler receive the source text as well.
+// Fields of type Cursor receive the beginning of the fragment while
+// fields of type Fragment receive the fragment itself.
+// Interface fields are populated with the implementation registered
+// for the kind of the fragment.
+//
+// The zero value of an Unmarshaler is ready to use
+type Unmarshaler struct {
+	impls map[FragmentKind][]reflect.Type
+}
+
+// Register registers the type of the given value as an implementation
+// of interface fields for fragments of the given kind.
+// Multiple implementations of different interfaces can be registered
+// for the same kind
+func (um *Unmarshaler) Register(kind FragmentKind, implementation interface{}) {
+	if um.impls == nil {
+		um.impls = map[FragmentKind][]reflect.Type{}
+	}
+	um.impls[kind] = append(um.impls[kind], reflect.TypeOf(implementation))
+}
+
+// Unmarshal populates the value pointed to by v with the given fragment
+func (um *Unmarshaler) Unmarshal(fragment Fragment, v interface{}) error {
+	if fragment == nil {
+		return errors.New("missing fragment")
+	}
+	val := reflect.ValueOf(v)
+	if val.Kind() != reflect.Ptr || val.IsNil() {
+		return fmt.Errorf(
+			"unmarshal target must be a non-nil pointer, got %s",
+			reflect.TypeOf(v),
+		)
+	}
+	return um.unmarshal(fragment, val.Elem(), nil)
+}
+
+// Unmarshal populates the value pointed to by v with the given fragment
+// without any registered interface implementations (see Unmarshaler)
+func Unmarshal(fragment Fragment, v interface{}) error {
+	return (&Unmarshaler{}).Unmarshal(fragment, v)
+}
+
+// errUnmarshal returns an unmarshaling error for the given fragment
+func errUnmarshal(frag Fragment, format string, a ...interface{}) error {
+	return &Err{Err: fmt.Errorf(format, a...), At: frag.Begin()}
+}
+
+// unmarshal populates v with the fragment. within holds the types of the
+// structs that are already being populated with the same fragment
+func (um *Unmarshaler) unmarshal(
+	frag Fragment,
+	v reflect.Value,
+	within []reflect.Type,
+) error {
+	switch v.Type() {
+	case typeCursor:
+		v.Set(reflect.ValueOf(frag.Begin()))
+		return nil
+	case typeFragment:
+		v.Set(reflect.ValueOf(frag))
+		return nil
+	}
+
+	if v.Kind() != reflect.Ptr && v.CanAddr() &&
+		v.Addr().Type().Implements(typeTextUnmarshaler) {
+		txt := v.Addr().Interface().(encoding.TextUnmarshaler)
+		if err := txt.UnmarshalText([]byte(string(frag.Src()))); err != nil {
+			return &Err{Err: err, At: frag.Begin()}
+		}
+		return nil
+	}
+
+	src := string(frag.Src())
+	switch v.Kind() {
+	case reflect.Ptr:
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		return um.unmarshal(frag, v.Elem(), within)
+
+	case reflect.Interface:
+		return um.unmarshalInterface(frag, v, within)
+
+	case reflect.Struct:
+		for _, tp := range within {
+			if tp == v.Type() {
+				// A field tagged "." refers to an enclosing struct
+				return errUnmarshal(
+					frag,
+					"cyclic self-reference of %s",
+					v.Type(),
+				)
+			}
+		}
+		return um.unmarshalStruct(frag, v, append(within, v.Type()))
+
+	case reflect.Slice:
+		switch v.Type().Elem().Kind() {
+		case reflect.Int32:
+			// Rune slice
+			v.Set(reflect.ValueOf(frag.Src()).Convert(v.Type()))
+			return nil
+		case reflect.Uint8:
+			// Byte slice
+			v.Set(reflect.ValueOf([]byte(src)).Convert(v.Type()))
+			return nil
+		}
+		elems := frag.Elements()
+		sl := reflect.MakeSlice(v.Type(), len(elems), len(elems))
+		for ix, el := range elems {
+			if err := um.unmarshal(el, sl.Index(ix), nil); err != nil {
+				return err
+			}
+		}
+		v.Set(sl)
+
+	case reflect.String:
+		v.SetString(src)
+
+	case reflect.Bool:
+		b, err := strconv.ParseBool(src)
+		if err != nil {
+			return errUnmarshal(frag, "%q isn't a valid %s", src, v.Type())
+		}
+		v.SetBool(b)
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, err := strconv.ParseInt(src, 0, v.Type().Bits())
+		if err != nil {
+			return errUnmarshal(frag, "%q isn't a valid %s", src, v.Type())
+		}
+		v.SetInt(i)
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32,
+		reflect.Uint64:
+		u, err := strconv.ParseUint(src, 0, v.Type().Bits())
+		if err != nil {
+			return errUnmarshal(frag, "%q isn't a valid %s", src, v.Type())
+		}
+		v.SetUint(u)
+
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(src, v.Type().Bits())
+		if err != nil {
+			return errUnmarshal(frag, "%q isn't a valid %s", src, v.Type())
+		}
+		v.SetFloat(f)
+
+	default:
+		return errUnmarshal(frag, "unsupported type: %s", v.Type())
+	}
+	return nil
+}
+
+func (um *Unmarshaler) unmarshalInterface(
+	frag Fragment,
+	v reflect.Value,
+	within []reflect.Type,
+) error {
+	for _, impl := range um.impls[frag.Kind()] {
+		if !impl.Implements(v.Type()) {
+			continue
+		}
+		val := reflect.New(impl).Elem()
+		if err := um.unmarshal(frag, val, within); err != nil {
+			return err
+		}
+		v.Set(val)
+		return nil
+	}
+	return errUnmarshal(
+		frag,
+		"no implementation of %s registered for fragment kind %d",
+		v.Type(),
+		frag.Kind(),
+	)
+}
+
+// fieldTag represents a parsed "llp" struct field tag
+type fieldTag struct {
+	self     bool
+	label    string
+	kind     FragmentKind
+	byKind   bool
+	optional bool
+}
+
+func parseFieldTag(tag string) (fieldTag, error) {
+	opts := strings.Split(tag, ",")
+	parsed := fieldTag{}
+	switch sel := opts[0]; {
+	case sel == "":
+		return parsed, errors.New("missing selector")
+	case sel == ".":
+		parsed.self = true
+	case strings.HasPrefix(sel, "kind="):
+		kind, err := strconv.Atoi(strings.TrimPrefix(sel, "kind="))
+		if err != nil {
+			return parsed, fmt.Errorf("invalid kind: %w", err)
+		}
+		parsed.kind, parsed.byKind = FragmentKind(kind), true
+	default:
+		parsed.label = sel
+	}
+	for _, opt := range opts[1:] {
+		switch opt {
+		case "optional":
+			parsed.optional = true
+		default:
+			return parsed, fmt.Errorf("unknown option %q", opt)
+		}
+	}
+	return parsed, nil
+}
+
+// matches returns all elements of the fragment selected by the tag
+func (tag fieldTag) matches(frag Fragment) []Fragment {
+	if tag.self {
+		return []Fragment{frag}
+	}
+	var matches []Fragment
+	if !tag.byKind {
+		if ct, ok := frag.(*Construct); ok {
+			matches = ct.Children(tag.label)
+		}
+		return matches
+	}
+	for _, el := range frag.Elements() {
+		if el.Kind() == tag.kind {
+			matches = append(matches, el)
+		}
+	}
+	return matches
+}
+
+func (um *Unmarshaler) unmarshalStruct(
+	frag Fragment,
+	v reflect.Value,
+	within []reflect.Type,
+) error {
+	tp := v.Type()
+	for ix := 0; ix < tp.NumField(); ix++ {
+		field := tp.Field(ix)
+		tagStr, ok := field.Tag.Lookup("llp")
+		if !ok || tagStr == "-" {
+			continue
+		}
+		if field.PkgPath != "" {
+			return fmt.Errorf(
+				"field %s.%s is tagged but unexported",
+				tp,
+				field.Name,
+			)
+		}
+		tag, err := parseFieldTag(tagStr)
+		if err != nil {
+			return fmt.Errorf(
+				"invalid tag of field %s.%s: %w",
+				tp,
+				field.Name,
+				err,
+			)
+		}
+
+		fieldVal := v.Field(ix)
+		matches := tag.matches(frag)
+
+		if field.Type.Kind() == reflect.Slice && !tag.self &&
+			!textSlice(field.Type) {
+			// Collect all matches
+			sl := reflect.MakeSlice(field.Type, len(matches), len(matches))
+			for mx, match := range matches {
+				if err := um.unmarshal(match, sl.Index(mx), nil); err != nil {
+					return err
+				}
+			}
+			fieldVal.Set(sl)
+			continue
+		}
+
+		switch {
+		case len(matches) > 1:
+			return errUnmarshal(
+				matches[1],
+				"unexpected fragment for field %s.%s, expected only one",
+				tp,
+				field.Name,
+			)
+		case len(matches) < 1:
+			if tag.optional || field.Type.Kind() == reflect.Ptr {
+				continue
+			}
+			return errUnmarshal(
+				frag,
+				"missing fragment for field %s.%s",
+				tp,
+				field.Name,
+			)
+		}
+		fieldWithin := within
+		if !tag.self {
+			// Only the fragment itself can refer to an enclosing struct
+			fieldWithin = nil
+		}
+		if err := um.unmarshal(matches[0], fieldVal, fieldWithin); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// textSlice returns true for rune and byte slices which are populated
+// with the source text of a fragment
+func textSlice(tp reflect.Type) bool {
+	kind := tp.Elem().Kind()
+	return kind == reflect.Int32 || kind == reflect.Uint8
+}
@@ -0,0 +1,243 @@
+package parser_test
+
+import (
+	"strings"
+	"testing"
+
+	llp "github.com/romshark/llparser"
+	"github.com/stretchr/testify/require"
+)
+
+type Server struct {
+	Begin llp.Cursor `llp:"."`
+	Src   string     `llp:"."`
+	Name  Name       `llp:"name"`
+	Port  uint16     `llp:"port"`
+	Debug *bool      `llp:"debug"`
+	Tags  []string   `llp:"tag"`
+	Ports []int      `llp:"kind=201"`
+}
+
+// Name is an upper-cased text
+type Name string
+
+func (n *Name) UnmarshalText(text []byte) error {
+	*n = Name(strings.ToUpper(string(text)))
+	return nil
+}
+
+func TestUnmarshal(t *testing.T) {
+	pr := newParser(t, newServerGrammar(), nil)
+	src := newSource("srv:8080:true[a,b]")
+	srv := &Server{}
+	require.NoError(t, llp.Unmarshal(mustParse(t, pr, src), srv))
+
+	CheckCursor(t, src, srv.Begin, 1, 1)
+	require.Equal(t, "srv:8080:true[a,b]", srv.Src)
+	require.Equal(t, Name("SRV"), srv.Name)
+	require.Equal(t, uint16(8080), srv.Port)
+	require.NotNil(t, srv.Debug)
+	require.True(t, *srv.Debug)
+	require.Equal(t, []string{"a", "b"}, srv.Tags)
+	require.Equal(t, []int{8080}, srv.Ports)
+}
+
+func TestUnmarshalOptional(t *testing.T) {
+	pr := newParser(t, newServerGrammar(), nil)
+	srv := &Server{}
+	mainFrag := mustParse(t, pr, newSource("srv:80"))
+	require.NoError(t, llp.Unmarshal(mainFrag, srv))
+	require.Equal(t, Name("SRV"), srv.Name)
+	require.Equal(t, uint16(80), srv.Port)
+	require.Nil(t, srv.Debug)
+	require.Len(t, srv.Tags, 0)
+}
+
+func TestUnmarshalBytes(t *testing.T) {
+	pr := newParser(t, newServerGrammar(), nil)
+	var v struct {
+		Src  []byte   `llp:"."`
+		Name []byte   `llp:"name"`
+		Tags [][]byte `llp:"tag"`
+	}
+	mainFrag := mustParse(t, pr, newSource("srv:80[a,b]"))
+	require.NoError(t, llp.Unmarshal(mainFrag, &v))
+	require.Equal(t, []byte("srv:80[a,b]"), v.Src)
+	require.Equal(t, []byte("srv"), v.Name)
+	require.Equal(t, [][]byte{[]byte("a"), []byte("b")}, v.Tags)
+}
+
+// Node represents an expression node
+type Node interface{ Eval(vars map[string]int) int }
+
+// Number represents a number literal
+type Number struct {
+	Value int `llp:"."`
+}
+
+func (n Number) Eval(map[string]int) int { return n.Value }
+
+// Variable represents a variable reference
+type Variable struct {
+	Name string `llp:"."`
+}
+
+func (v *Variable) Eval(vars map[string]int) int { return vars[v.Name] }
+
+// Sum represents a sum of operands
+type Sum struct {
+	Operands []Node `llp:"operand"`
+}
+
+func (s *Sum) Eval(vars map[string]int) int {
+	sum := 0
+	for _, op := range s.Operands {
+		sum += op.Eval(vars)
+	}
+	return sum
+}
+
+func TestUnmarshalInterface(t *testing.T) {
+	sum := &llp.Rule{Designation: "sum", Kind: kindSum}
+	operand := llp.Label{Name: "operand", Pattern: llp.Either{
+		termNumber,
+		&llp.Rule{
+			Designation: "variable",
+			Kind:        kindVariable,
+			Pattern:     termLatinWord,
+		},
+		llp.Sequence{
+			llp.Shaped{
+				Shape:   llp.ShapeHidden,
+				Pattern: &llp.Exact{Expectation: []rune("(")},
+			},
+			sum,
+			llp.Shaped{
+				Shape:   llp.ShapeHidden,
+				Pattern: &llp.Exact{Expectation: []rune(")")},
+			},
+		},
+	}}
+	sum.Pattern = llp.Sequence{
+		operand,
+		&llp.Repeated{Pattern: llp.Sequence{
+			&llp.Exact{Expectation: []rune("+")},
+			operand,
+		}},
+	}
+
+	mainFrag, err := newParser(t, sum, nil).Parse(newSource("1+x+(2+y)"))
+	require.NoError(t, err)
+
+	um := &llp.Unmarshaler{}
+	um.Register(kindNumber, Number{})
+	um.Register(kindVariable, &Variable{})
+	um.Register(kindSum, &Sum{})
+
+	var node Node
+	require.NoError(t, um.Unmarshal(mainFrag, &node))
+	require.IsType(t, &Sum{}, node)
+	require.Len(t, node.(*Sum).Operands, 3)
+	require.Equal(t, 1+3+2+4, node.Eval(map[string]int{"x": 3, "y": 4}))
+
+	t.Run("NotRegistered", func(t *testing.T) {
+		um := &llp.Unmarshaler{}
+		um.Register(kindNumber, Number{})
+		um.Register(kindSum, &Sum{})
+
+		var node Node
+		err := um.Unmarshal(mainFrag, &node)
+		require.Error(t, err)
+		require.IsType(t, &llp.Err{}, err)
+		require.Equal(
+			t,
+			"no implementation of parser_test.Node registered "+
+				"for fragment kind 203 at test.txt:1:3",
+			err.Error(),
+		)
+	})
+}
+
+func TestUnmarshalErrors(t *testing.T) {
+	pr := newParser(t, newServerGrammar(), nil)
+
+	t.Run("InvalidNumber", func(t *testing.T) {
+		srv := &Server{}
+		err := llp.Unmarshal(mustParse(t, pr, newSource("srv:99999")), srv)
+		require.Error(t, err)
+		require.IsType(t, &llp.Err{}, err)
+		require.Equal(
+			t,
+			`"99999" isn't a valid uint16 at test.txt:1:5`,
+			err.Error(),
+		)
+	})
+
+	t.Run("InvalidBool", func(t *testing.T) {
+		srv := &Server{}
+		err := llp.Unmarshal(mustParse(t, pr, newSource("srv:80:maybe")), srv)
+		require.Error(t, err)
+		require.Equal(
+			t,
+			`"maybe" isn't a valid bool at test.txt:1:8`,
+			err.Error(),
+		)
+	})
+
+	t.Run("Missing", func(t *testing.T) {
+		type Host struct {
+			Host string `llp:"host"`
+		}
+		err := llp.Unmarshal(mustParse(t, pr, newSource("srv:80")), &Host{})
+		require.Error(t, err)
+		require.Equal(
+			t,
+			"missing fragment for field parser_test.Host.Host at test.txt:1:1",
+			err.Error(),
+		)
+	})
+
+	t.Run("Ambiguous", func(t *testing.T) {
+		var v struct {
+			Tag string `llp:"tag"`
+		}
+		src := newSource("srv:80[a,b]")
+		err := llp.Unmarshal(mustParse(t, pr, src), &v)
+		require.Error(t, err)
+		require.IsType(t, &llp.Err{}, err)
+		CheckCursor(t, src, err.(*llp.Err).At, 1, 10)
+	})
+
+	t.Run("InvalidTag", func(t *testing.T) {
+		var v struct {
+			Port int `llp:"kind=x"`
+		}
+		err := llp.Unmarshal(mustParse(t, pr, newSource("srv:80")), &v)
+		require.Error(t, err)
+	})
+
+	t.Run("Cycle", func(t *testing.T) {
+		type Loop struct {
+			Name string `llp:"name"`
+			Self *Loop  `llp:"."`
+		}
+		err := llp.Unmarshal(mustParse(t, pr, newSource("srv:80")), &Loop{})
+		require.Error(t, err)
+		require.IsType(t, &llp.Err{}, err)
+		require.Equal(
+			t,
+			"cyclic self-reference of parser_test.Loop at test.txt:1:1",
+			err.Error(),
+		)
+	})
+
+	t.Run("InvalidTarget", func(t *testing.T) {
+		err := llp.Unmarshal(mustParse(t, pr, newSource("srv:80")), Server{})
+		require.Error(t, err)
+		require.Equal(
+			t,
+			"unmarshal target must be a non-nil pointer, got parser_test.Server",
+			err.Error(),
+		)
+	})
+}
@@ -12,7 +12,16 @@ func validateRule(
 	if ptr.Pattern == nil {
 		return fmt.Errorf("rule %p is missing a pattern", ptr)
 	}
-	return validatePattern(ptr.Pattern, validated)
+	if ptr.Shape > ShapeCollapse {
+		return fmt.Errorf("rule %p has an invalid shape (%d)", ptr, ptr.Shape)
+	}
+	if err := validatePattern(ptr.Pattern, validated); err != nil {
+		return err
+	}
+	if ptr.ErrRule != nil {
+		return validatePattern(ptr.ErrRule, validated)
+	}
+	return nil
 }
 
 func validateSequence(
@@ -71,6 +80,8 @@ func validateEither(
 			checkDuplicate = true
 		case *Repeated:
 			checkDuplicate = true
+		case *Predicate:
+			checkDuplicate = true
 		}
 
 		if checkDuplicate {
@@ -101,6 +112,32 @@ func validateNot(
 	return validatePattern(ptr.Pattern, validated)
 }
 
+func validateLabel(
+	ptr Label,
+	validated map[Pattern]struct{},
+) error {
+	if ptr.Name == "" {
+		return fmt.Errorf("label is missing a name")
+	}
+	if ptr.Pattern == nil {
+		return fmt.Errorf("label %q is missing a pattern", ptr.Name)
+	}
+	return validatePattern(ptr.Pattern, validated)
+}
+
+func validateShaped(
+	ptr Shaped,
+	validated map[Pattern]struct{},
+) error {
+	if ptr.Shape > ShapeCollapse {
+		return fmt.Errorf("shaped pattern has an invalid shape (%d)", ptr.Shape)
+	}
+	if ptr.Pattern == nil {
+		return fmt.Errorf("shaped pattern is missing a pattern")
+	}
+	return validatePattern(ptr.Pattern, validated)
+}
+
 func validateLexed(ptr *Lexed) error {
 	if ptr.Fn == nil {
 		return fmt.Errorf("lexed-terminal %p is missing the lexer function", ptr)
@@ -108,6 +145,13 @@ func validateLexed(ptr *Lexed) error {
 	return nil
 }
 
+func validatePredicate(ptr *Predicate) error {
+	if ptr.Fn == nil {
+		return fmt.Errorf("predicate %p is missing the predicate function", ptr)
+	}
+	return nil
+}
+
 func validateExact(ptr *Exact) error {
 	if len(ptr.Expectation) < 1 {
 		return fmt.Errorf("exact-terminal %p is missing an expectation", ptr)
@@ -136,6 +180,8 @@ func validatePattern(ptr Pattern, validated map[Pattern]struct{}) error {
 	switch ptr := ptr.(type) {
 	case nil:
 		return nil
+	case Cut:
+		return nil
 	case *Rule:
 		if isValidated() {
 			return nil
@@ -162,6 +208,14 @@ func validatePattern(ptr Pattern, validated map[Pattern]struct{}) error {
 		if err := validateNot(ptr, validated); err != nil {
 			return err
 		}
+	case Label:
+		if err := validateLabel(ptr, validated); err != nil {
+			return err
+		}
+	case Shaped:
+		if err := validateShaped(ptr, validated); err != nil {
+			return err
+		}
 	case *Lexed:
 		if isValidated() {
 			return nil
@@ -176,6 +230,13 @@ func validatePattern(ptr Pattern, validated map[Pattern]struct{}) error {
 		if err := validateExact(ptr); err != nil {
 			return err
 		}
+	case *Predicate:
+		if isValidated() {
+			return nil
+		}
+		if err := validatePredicate(ptr); err != nil {
+			return err
+		}
 	default:
 		return fmt.Errorf(
 			"unsupported pattern type: %s",
@@ -0,0 +1,145 @@
+package parser
+
+// Visit defines how walking continues after a fragment was visited
+type Visit int
+
+const (
+	// VisitContinue continues walking
+	VisitContinue Visit = iota
+
+	// VisitSkip skips the elements of the visited fragment.
+	// When returned from a post-order callback it skips
+	// the remaining siblings of the visited fragment
+	VisitSkip
+
+	// VisitStop stops walking
+	VisitStop
+)
+
+// Walk traverses the fragment tree depth-first calling pre before and post
+// after the elements of a fragment are walked. Either callback may be nil.
+// The post-order callback isn't called for fragments skipped by pre.
+// Returns false if walking was stopped
+func Walk(
+	fragment Fragment,
+	pre func(Fragment) Visit,
+	post func(Fragment) Visit,
+) bool {
+	return walk(fragment, pre, post) != VisitStop
+}
+
+func walk(
+	frag Fragment,
+	pre func(Fragment) Visit,
+	post func(Fragment) Visit,
+) Visit {
+	if pre != nil {
+		switch pre(frag) {
+		case VisitSkip:
+			return VisitContinue
+		case VisitStop:
+			return VisitStop
+		}
+	}
+	for _, el := range frag.Elements() {
+		switch walk(el, pre, post) {
+		case VisitStop:
+			return VisitStop
+		case VisitSkip:
+			// Skip the remaining siblings
+			return postVisit(frag, post)
+		}
+	}
+	return postVisit(frag, post)
+}
+
+func postVisit(frag Fragment, post func(Fragment) Visit) Visit {
+	if post == nil {
+		return VisitContinue
+	}
+	return post(frag)
+}
+
+// Inspect traverses the fragment tree depth-first in pre-order.
+// The elements of a fragment are skipped if fn returns false
+func Inspect(fragment Fragment, fn func(Fragment) bool) {
+	walk(fragment, func(frag Fragment) Visit {
+		if !fn(frag) {
+			return VisitSkip
+		}
+		return VisitContinue
+	}, nil)
+}
+
+// FindKinds returns all descendants of the fragment
+// of either of the given kinds in pre-order
+func FindKinds(fragment Fragment, kinds ...FragmentKind) []Fragment {
+	var found []Fragment
+	for _, el := range fragment.Elements() {
+		Inspect(el, func(frag Fragment) bool {
+			for _, kind := range kinds {
+				if frag.Kind() == kind {
+					found = append(found, frag)
+					break
+				}
+			}
+			return true
+		})
+	}
+	return found
+}
+
+// covers returns true if the fragment covers the given cursor
+func covers(frag Fragment, cursor Cursor) bool {
+	return frag.Begin().Index <= cursor.Index &&
+		cursor.Index < frag.End().Index
+}
+
+// FragmentAt returns the innermost fragment covering the given cursor
+// or nil if the cursor is outside the fragment
+func FragmentAt(fragment Fragment, cursor Cursor) Fragment {
+	if !covers(fragment, cursor) {
+		return nil
+	}
+	for {
+		var next Fragment
+		for _, el := range fragment.Elements() {
+			if covers(el, cursor) {
+				next = el
+				break
+			}
+		}
+		if next == nil {
+			return fragment
+		}
+		fragment = next
+	}
+}
+
+// PathTo returns the ancestor chain from the root fragment down to
+// and including the target fragment or nil if the target isn't part of
+// the root fragment's tree
+func PathTo(root Fragment, target Fragment) []Fragment {
+	var path []Fragment
+	found := false
+	walk(root, func(frag Fragment) Visit {
+		if frag.Begin().Index > target.Begin().Index ||
+			frag.End().Index < target.End().Index {
+			// The target can't be part of this subtree
+			return VisitSkip
+		}
+		path = append(path, frag)
+		if frag == target {
+			found = true
+			return VisitStop
+		}
+		return VisitContinue
+	}, func(frag Fragment) Visit {
+		path = path[:len(path)-1]
+		return VisitContinue
+	})
+	if !found {
+		return nil
+	}
+	return path
+}
@@ -0,0 +1,159 @@
+package parser_test
+
+import (
+	"testing"
+
+	llp "github.com/romshark/llparser"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWalk(t *testing.T) {
+	pr := newParser(t, newListGrammar(), nil)
+	mainFrag := mustParse(t, pr, newSource("a,(b,c),d"))
+
+	var pre, post []llp.Fragment
+	require.True(t, llp.Walk(mainFrag, func(f llp.Fragment) llp.Visit {
+		pre = append(pre, f)
+		return llp.VisitContinue
+	}, func(f llp.Fragment) llp.Visit {
+		post = append(post, f)
+		return llp.VisitContinue
+	}))
+
+	require.Equal(t, []string{
+		"a,(b,c),d", "a", ",", "(b,c)", "(", "b", ",", "c", ")", ",", "d",
+	}, fragSrc(pre))
+	require.Equal(t, []string{
+		"a", ",", "(", "b", ",", "c", ")", "(b,c)", ",", "d", "a,(b,c),d",
+	}, fragSrc(post))
+}
+
+func TestWalkSkip(t *testing.T) {
+	pr := newParser(t, newListGrammar(), nil)
+	mainFrag := mustParse(t, pr, newSource("a,(b,c),d"))
+
+	t.Run("Pre", func(t *testing.T) {
+		var pre, post []llp.Fragment
+		require.True(t, llp.Walk(mainFrag, func(f llp.Fragment) llp.Visit {
+			pre = append(pre, f)
+			if f.Kind() == kindGroup {
+				return llp.VisitSkip
+			}
+			return llp.VisitContinue
+		}, func(f llp.Fragment) llp.Visit {
+			post = append(post, f)
+			return llp.VisitContinue
+		}))
+		require.Equal(t, []string{
+			"a,(b,c),d", "a", ",", "(b,c)", ",", "d",
+		}, fragSrc(pre))
+		require.Equal(t, []string{
+			"a", ",", ",", "d", "a,(b,c),d",
+		}, fragSrc(post))
+	})
+
+	t.Run("Post", func(t *testing.T) {
+		// Skip the siblings following the first word of the group
+		var pre []llp.Fragment
+		require.True(t, llp.Walk(mainFrag, func(f llp.Fragment) llp.Visit {
+			pre = append(pre, f)
+			return llp.VisitContinue
+		}, func(f llp.Fragment) llp.Visit {
+			if string(f.Src()) == "b" {
+				return llp.VisitSkip
+			}
+			return llp.VisitContinue
+		}))
+		require.Equal(t, []string{
+			"a,(b,c),d", "a", ",", "(b,c)", "(", "b", ",", "d",
+		}, fragSrc(pre))
+	})
+}
+
+func TestWalkStop(t *testing.T) {
+	pr := newParser(t, newListGrammar(), nil)
+	mainFrag := mustParse(t, pr, newSource("a,(b,c),d"))
+
+	var pre []llp.Fragment
+	require.False(t, llp.Walk(mainFrag, func(f llp.Fragment) llp.Visit {
+		pre = append(pre, f)
+		if string(f.Src()) == "b" {
+			return llp.VisitStop
+		}
+		return llp.VisitContinue
+	}, nil))
+	require.Equal(t, []string{
+		"a,(b,c),d", "a", ",", "(b,c)", "(", "b",
+	}, fragSrc(pre))
+}
+
+func TestInspect(t *testing.T) {
+	pr := newParser(t, newListGrammar(), nil)
+	mainFrag := mustParse(t, pr, newSource("a,(b,(c)),d"))
+
+	var words []llp.Fragment
+	llp.Inspect(mainFrag, func(f llp.Fragment) bool {
+		if f.Kind() == FrWord {
+			words = append(words, f)
+		}
+		// Don't descend into nested groups
+		return f.Kind() != kindGroup || string(f.Src()) != "(c)"
+	})
+	require.Equal(t, []string{"a", "b", "d"}, fragSrc(words))
+}
+
+func TestFindKinds(t *testing.T) {
+	pr := newParser(t, newListGrammar(), nil)
+	mainFrag := mustParse(t, pr, newSource("a,(b,(c)),d"))
+
+	require.Equal(
+		t,
+		[]string{"(b,(c))", "(c)"},
+		fragSrc(llp.FindKinds(mainFrag, kindGroup)),
+	)
+	require.Equal(
+		t,
+		[]string{"a", "(b,(c))", "b", "(c)", "c", "d"},
+		fragSrc(llp.FindKinds(mainFrag, kindGroup, FrWord)),
+	)
+
+	// The fragment itself isn't included
+	require.Len(t, llp.FindKinds(mainFrag, kindList), 0)
+}
+
+func TestFragmentAt(t *testing.T) {
+	pr := newParser(t, newListGrammar(), nil)
+	src := newSource("a,(b,(c)),d")
+	mainFrag := mustParse(t, pr, src)
+
+	at := func(index uint) llp.Fragment {
+		return llp.FragmentAt(mainFrag, llp.Cursor{Index: index, File: src})
+	}
+
+	checkFrag(t, src, at(0), FrWord, C{1, 1}, C{1, 2}, 0)
+	checkFrag(t, src, at(6), FrWord, C{1, 7}, C{1, 8}, 0)
+	checkFrag(t, src, at(7), 0, C{1, 8}, C{1, 9}, 0)
+	require.Nil(t, at(11))
+}
+
+func TestPathTo(t *testing.T) {
+	pr := newParser(t, newListGrammar(), nil)
+	src := newSource("a,(b,(c)),d")
+	mainFrag := mustParse(t, pr, src)
+
+	c := llp.FragmentAt(mainFrag, llp.Cursor{Index: 6, File: src})
+	require.Equal(
+		t,
+		[]string{"a,(b,(c)),d", "(b,(c))", "(c)", "c"},
+		fragSrc(llp.PathTo(mainFrag, c)),
+	)
+	require.Equal(
+		t,
+		[]string{"a,(b,(c)),d"},
+		fragSrc(llp.PathTo(mainFrag, mainFrag)),
+	)
+
+	// Not part of the tree
+	other := mustParse(t, pr, newSource("a,(b,(c)),d"))
+	require.Nil(t, llp.PathTo(mainFrag, other))
+}